@@ -0,0 +1,354 @@
+// Copyright © 2023 tsuru-client authors
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package archiver builds the gzipped tarballs sent to tsuru on deploys.
+package archiver
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	gitignore "github.com/sabhiram/go-gitignore"
+	"github.com/spf13/afero"
+)
+
+var ErrMissingFilesToArchive = errors.New("missing files to archive")
+
+type Options struct {
+	// CompressionLevel defaults to gzip.DefaultCompression
+	CompressionLevel *int
+	// IgnoreFiles are files with gitignore-like patterns (defaults to none)
+	IgnoreFiles []string
+	// Stderr receives warnings and debug messages (defaults to io.Discard)
+	Stderr io.Writer
+	// Stats, if not nil, is filled with the files considered and the archive sizes
+	Stats *Stats
+	// ContentHash, if not nil, receives the name, mode and content of every
+	// archived file (but not their timestamps), so archives with the same files
+	// have the same hash
+	ContentHash hash.Hash
+}
+
+// Stats describes an archive built by Archive.
+type Stats struct {
+	Files []FileStat `json:"files"`
+	// Size is the size of the tarball before compression
+	Size int64 `json:"size"`
+	// CompressedSize is the size written to the destination
+	CompressedSize int64 `json:"compressedSize"`
+}
+
+// FileStat describes a file considered for the archive. Status is one of
+// "added", "ignored", "duplicated", "outside" or "unsupported".
+type FileStat struct {
+	Path   string `json:"path"`
+	Name   string `json:"name,omitempty"`
+	Size   int64  `json:"size"`
+	Status string `json:"status"`
+}
+
+// Count returns how many files have the given status.
+func (s *Stats) Count(status string) int {
+	count := 0
+	for _, f := range s.Files {
+		if f.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+func (s *Stats) add(path, name string, size int64, status string) {
+	if s == nil {
+		return
+	}
+	s.Files = append(s.Files, FileStat{Path: path, Name: name, Size: size, Status: status})
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// DefaultOptions returns the options used by "app deploy".
+func DefaultOptions(stderr io.Writer) Options {
+	return Options{
+		CompressionLevel: func(lvl int) *int { return &lvl }(gzip.BestCompression),
+		IgnoreFiles:      []string{".tsuruignore"},
+		Stderr:           stderr,
+	}
+}
+
+// Archive writes to dst a gzipped tarball with the given paths read from fsys.
+// When a single directory is given (or filesOnly is set), its content is placed
+// at the root of the archive. With filesOnly, every file is placed at the root
+// of the archive, regardless of its base directory.
+func Archive(fsys afero.Fs, dst io.Writer, filesOnly bool, paths []string, opts Options) error {
+	if dst == nil {
+		return fmt.Errorf("destination cannot be nil")
+	}
+	if len(paths) == 0 {
+		return fmt.Errorf("paths cannot be empty")
+	}
+	if opts.Stderr == nil {
+		opts.Stderr = io.Discard
+	}
+
+	ignore, negated, err := compileIgnoreFiles(fsys, opts.IgnoreFiles, opts.Stderr)
+	if err != nil {
+		return err
+	}
+
+	if opts.CompressionLevel == nil {
+		opts.CompressionLevel = func(n int) *int { return &n }(gzip.DefaultCompression)
+	}
+	compressed := &countingWriter{w: dst}
+	zw, err := gzip.NewWriterLevel(compressed, *opts.CompressionLevel)
+	if err != nil {
+		return err
+	}
+	uncompressed := &countingWriter{w: zw}
+	tw := tar.NewWriter(uncompressed)
+
+	a := &archiver{
+		fsys:            fsys,
+		ignore:          ignore,
+		stderr:          opts.Stderr,
+		files:           map[string]struct{}{},
+		stats:           opts.Stats,
+		hash:            opts.ContentHash,
+		skipIgnoredDirs: !negated, // negated patterns (eg: !node_modules/keep.js) may re-include their files
+	}
+	if err = a.archive(tw, filesOnly, paths); err != nil {
+		return err
+	}
+	if err = tw.Close(); err != nil {
+		return err
+	}
+	if err = zw.Close(); err != nil {
+		return err
+	}
+	if opts.Stats != nil {
+		opts.Stats.Size = uncompressed.n
+		opts.Stats.CompressedSize = compressed.n
+	}
+	return nil
+}
+
+// compileIgnoreFiles returns the patterns of the ignore files, and whether
+// any of them is negated.
+func compileIgnoreFiles(fsys afero.Fs, ignoreFiles []string, stderr io.Writer) (*gitignore.GitIgnore, bool, error) {
+	var ignoreLines []string
+	var negated bool
+	for _, ignoreFile := range ignoreFiles {
+		data, err := afero.ReadFile(fsys, ignoreFile)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to read ignore file %q: %w", ignoreFile, err)
+		}
+		fmt.Fprintf(stderr, "Using pattern(s) from %q to include/exclude files...\n", ignoreFile)
+		for _, line := range strings.Split(string(data), "\n") {
+			ignoreLines = append(ignoreLines, line)
+			negated = negated || strings.HasPrefix(strings.TrimSpace(line), "!")
+		}
+	}
+
+	ignore, err := gitignore.CompileIgnoreLines(ignoreLines...)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to compile all ignore patterns: %w", err)
+	}
+	return ignore, negated, nil
+}
+
+type archiver struct {
+	fsys   afero.Fs
+	ignore *gitignore.GitIgnore
+	stderr io.Writer
+	files  map[string]struct{}
+	stats  *Stats
+	hash   hash.Hash
+
+	skipIgnoredDirs bool // whether the ignored directories are left unwalked
+}
+
+func (a *archiver) archive(tw *tar.Writer, filesOnly bool, paths []string) error {
+	workingDir, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get the current directory: %w", err)
+	}
+
+	var added int
+	for _, path := range paths {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return fmt.Errorf("failed to get the absolute filename of %q: %w", path, err)
+		}
+		if rel, err := filepath.Rel(workingDir, abs); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(os.PathSeparator)) {
+			fmt.Fprintf(a.stderr, "WARNING: skipping file %q since you cannot add files outside the current directory\n", path)
+			a.stats.add(path, "", 0, "outside")
+			continue
+		}
+
+		fi, err := a.lstat(path)
+		if err != nil {
+			return err
+		}
+
+		var n int
+		if fi.IsDir() {
+			// when either a single directory is given or files only is turned on,
+			// the directory is considered the root of the archive (backward-compatibility).
+			asRoot := filesOnly || len(paths) == 1
+			n, err = a.addDir(tw, path, asRoot)
+		} else {
+			n, err = a.addFile(tw, filesOnly, path, filepath.Clean(path), fi)
+		}
+		if err != nil {
+			return err
+		}
+		added += n
+	}
+
+	if added == 0 {
+		return ErrMissingFilesToArchive
+	}
+	return nil
+}
+
+func (a *archiver) addDir(tw *tar.Writer, root string, asRoot bool) (int, error) {
+	var added int
+	err := afero.Walk(a.fsys, root, func(path string, fi os.FileInfo, err error) error {
+		if err != nil { // fail fast
+			return err
+		}
+
+		name := filepath.Clean(path)
+		if asRoot {
+			if name, err = filepath.Rel(root, path); err != nil {
+				return err
+			}
+		}
+		if fi.IsDir() && name != "." && a.skipIgnoredDirs && (a.ignore.MatchesPath(name) || a.ignore.MatchesPath(name+"/")) {
+			fmt.Fprintf(a.stderr, "Directory %q matches with some pattern provided in the ignore file... skipping it.\n", path)
+			a.stats.add(path, name, fi.Size(), "ignored")
+			return filepath.SkipDir
+		}
+
+		n, err := a.addFile(tw, false, path, name, fi)
+		if err != nil {
+			return err
+		}
+		added += n
+		return nil
+	})
+	return added, err
+}
+
+func (a *archiver) addFile(tw *tar.Writer, filesOnly bool, path, name string, fi os.FileInfo) (int, error) {
+	isDir, isRegular, isSymlink := fi.IsDir(), fi.Mode().IsRegular(), fi.Mode()&os.ModeSymlink == os.ModeSymlink
+
+	if !isDir && !isRegular && !isSymlink {
+		fmt.Fprintf(a.stderr, "WARNING: Skipping file %q due to unsupported file type.\n", path)
+		a.stats.add(path, "", 0, "unsupported")
+		return 0, nil
+	}
+	if isDir && filesOnly { // there's no need to create dirs in files only
+		return 0, nil
+	}
+	if filesOnly {
+		name = fi.Name()
+	}
+	if name == "." { // skipping root dir
+		return 0, nil
+	}
+
+	if a.ignore.MatchesPath(name) {
+		fmt.Fprintf(a.stderr, "File %q matches with some pattern provided in the ignore file... skipping it.\n", path)
+		a.stats.add(path, name, fi.Size(), "ignored")
+		return 0, nil
+	}
+
+	var linkname string
+	if isSymlink {
+		target, err := a.readlink(path)
+		if err != nil {
+			return 0, err
+		}
+		linkname = target
+	}
+
+	h, err := tar.FileInfoHeader(fi, linkname)
+	if err != nil {
+		return 0, err
+	}
+	h.Name = filepath.ToSlash(name)
+	if isDir {
+		h.Name += "/"
+	}
+
+	if _, found := a.files[h.Name]; found {
+		fmt.Fprintf(a.stderr, "Skipping file %q as it already exists in the current directory.\n", path)
+		a.stats.add(path, h.Name, h.Size, "duplicated")
+		return 0, nil
+	}
+	a.files[h.Name] = struct{}{}
+	a.stats.add(path, h.Name, h.Size, "added")
+
+	if err = tw.WriteHeader(h); err != nil {
+		return 0, err
+	}
+	var dst io.Writer = tw
+	if a.hash != nil {
+		fmt.Fprintf(a.hash, "%s\x00%o\x00%s\x00%d\x00", h.Name, h.Mode, h.Linkname, h.Size)
+		dst = io.MultiWriter(tw, a.hash)
+	}
+	if isDir || isSymlink { // there's no data to copy from dir or symlink
+		return 1, nil
+	}
+
+	f, err := a.fsys.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	written, err := io.CopyN(dst, f, h.Size)
+	if err != nil {
+		return 0, err
+	}
+	if written < h.Size {
+		return 0, io.ErrShortWrite
+	}
+	return 1, nil
+}
+
+func (a *archiver) lstat(path string) (os.FileInfo, error) {
+	if lstater, ok := a.fsys.(afero.Lstater); ok {
+		fi, _, err := lstater.LstatIfPossible(path)
+		return fi, err
+	}
+	return a.fsys.Stat(path)
+}
+
+func (a *archiver) readlink(path string) (string, error) {
+	if reader, ok := a.fsys.(afero.LinkReader); ok {
+		return reader.ReadlinkIfPossible(path)
+	}
+	return "", fmt.Errorf("symlinks are not supported by the filesystem: %q", path)
+}
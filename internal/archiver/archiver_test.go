@@ -0,0 +1,188 @@
+// Copyright © 2023 tsuru-client authors
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package archiver
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+func testingFs(t *testing.T) afero.Fs {
+	fsys := afero.NewMemMapFs()
+	for name, content := range map[string]string{
+		"mysite/index.html":      "<html></html>",
+		"mysite/Procfile":        "web: ./run",
+		"mysite/static/main.css": "body {}",
+		"mysite/debug.log":       "some log",
+		"other/Procfile":         "web: ./other",
+		"tsuru.yaml":             "healthcheck: {}",
+	} {
+		assert.NoError(t, afero.WriteFile(fsys, name, []byte(content), 0644))
+	}
+	return fsys
+}
+
+func extractTarGz(t *testing.T, r io.Reader) map[string]string {
+	zr, err := gzip.NewReader(r)
+	if !assert.NoError(t, err) {
+		return nil
+	}
+	tr := tar.NewReader(zr)
+	files := map[string]string{}
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if !assert.NoError(t, err) {
+			break
+		}
+		data, err := io.ReadAll(tr)
+		assert.NoError(t, err)
+		files[h.Name] = string(data)
+	}
+	return files
+}
+
+func sortedNames(files map[string]string) []string {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestArchiveSingleDirIsRoot(t *testing.T) {
+	fsys := testingFs(t)
+	var buf bytes.Buffer
+	err := Archive(fsys, &buf, false, []string{"mysite"}, Options{})
+	assert.NoError(t, err)
+
+	files := extractTarGz(t, &buf)
+	assert.Equal(t, []string{"Procfile", "debug.log", "index.html", "static/", "static/main.css"}, sortedNames(files))
+	assert.Equal(t, "web: ./run", files["Procfile"])
+}
+
+func TestArchiveManyPathsKeepTree(t *testing.T) {
+	fsys := testingFs(t)
+	var buf bytes.Buffer
+	err := Archive(fsys, &buf, false, []string{"mysite/static", "./tsuru.yaml"}, Options{})
+	assert.NoError(t, err)
+
+	files := extractTarGz(t, &buf)
+	assert.Equal(t, []string{"mysite/static/", "mysite/static/main.css", "tsuru.yaml"}, sortedNames(files))
+}
+
+func TestArchiveFilesOnly(t *testing.T) {
+	fsys := testingFs(t)
+	stderr := strings.Builder{}
+	var buf bytes.Buffer
+	err := Archive(fsys, &buf, true, []string{"mysite/Procfile", "other/Procfile", "mysite/static/main.css"}, Options{Stderr: &stderr})
+	assert.NoError(t, err)
+
+	files := extractTarGz(t, &buf)
+	assert.Equal(t, []string{"Procfile", "main.css"}, sortedNames(files))
+	assert.Equal(t, "web: ./run", files["Procfile"])
+	assert.Contains(t, stderr.String(), `Skipping file "other/Procfile" as it already exists`)
+}
+
+func TestArchiveIgnoreFile(t *testing.T) {
+	fsys := testingFs(t)
+	assert.NoError(t, afero.WriteFile(fsys, ".tsuruignore", []byte("*.log\nstatic\n"), 0644))
+	stderr := strings.Builder{}
+	var buf bytes.Buffer
+	err := Archive(fsys, &buf, false, []string{"mysite"}, DefaultOptions(&stderr))
+	assert.NoError(t, err)
+
+	files := extractTarGz(t, &buf)
+	assert.Equal(t, []string{"Procfile", "index.html"}, sortedNames(files))
+	assert.Contains(t, stderr.String(), `Using pattern(s) from ".tsuruignore"`)
+}
+
+func TestArchiveSkipsIgnoredDirs(t *testing.T) {
+	fsys := testingFs(t)
+	assert.NoError(t, afero.WriteFile(fsys, "mysite/static/vendor/lib.js", []byte("lib()"), 0644))
+	assert.NoError(t, afero.WriteFile(fsys, ".tsuruignore", []byte("static/\n"), 0644))
+	opts := DefaultOptions(nil)
+	opts.Stats = &Stats{}
+	err := Archive(fsys, io.Discard, false, []string{"mysite"}, opts)
+	assert.NoError(t, err)
+	var ignored []string
+	for _, f := range opts.Stats.Files {
+		if f.Status == "ignored" {
+			ignored = append(ignored, f.Path)
+		}
+	}
+	assert.Equal(t, []string{"mysite/static"}, ignored)
+
+	// a negated pattern may re-include files of the directory
+	assert.NoError(t, afero.WriteFile(fsys, ".tsuruignore", []byte("static/\n!static/main.css\n"), 0644))
+	var buf bytes.Buffer
+	err = Archive(fsys, &buf, false, []string{"mysite"}, DefaultOptions(nil))
+	assert.NoError(t, err)
+	assert.Contains(t, extractTarGz(t, &buf), "static/main.css")
+}
+
+func TestArchiveStats(t *testing.T) {
+	fsys := testingFs(t)
+	assert.NoError(t, afero.WriteFile(fsys, ".tsuruignore", []byte("*.log\n"), 0644))
+	opts := DefaultOptions(nil)
+	opts.Stats = &Stats{}
+	var buf bytes.Buffer
+	err := Archive(fsys, &buf, true, []string{"mysite/Procfile", "other/Procfile", "mysite/debug.log"}, opts)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []FileStat{
+		{Path: "mysite/Procfile", Name: "Procfile", Size: 10, Status: "added"},
+		{Path: "other/Procfile", Name: "Procfile", Size: 12, Status: "duplicated"},
+		{Path: "mysite/debug.log", Name: "debug.log", Size: 8, Status: "ignored"},
+	}, opts.Stats.Files)
+	assert.Equal(t, 1, opts.Stats.Count("added"))
+	assert.Equal(t, int64(buf.Len()), opts.Stats.CompressedSize)
+	assert.Greater(t, opts.Stats.Size, opts.Stats.CompressedSize)
+}
+
+func TestArchiveContentHash(t *testing.T) {
+	fsys := testingFs(t)
+	contentHash := func() string {
+		opts := DefaultOptions(nil)
+		opts.ContentHash = sha256.New()
+		assert.NoError(t, Archive(fsys, io.Discard, false, []string{"mysite"}, opts))
+		return hex.EncodeToString(opts.ContentHash.Sum(nil))
+	}
+
+	first := contentHash()
+	assert.NoError(t, fsys.Chtimes("mysite/index.html", time.Now().Add(time.Hour), time.Now().Add(time.Hour)))
+	assert.Equal(t, first, contentHash(), "timestamps must not change the hash")
+	assert.NoError(t, afero.WriteFile(fsys, "mysite/index.html", []byte("<html>v2</html>"), 0644))
+	assert.NotEqual(t, first, contentHash())
+}
+
+func TestArchiveOutsideWorkingDir(t *testing.T) {
+	fsys := testingFs(t)
+	stderr := strings.Builder{}
+	err := Archive(fsys, io.Discard, false, []string{"../mysite"}, Options{Stderr: &stderr})
+	assert.ErrorIs(t, err, ErrMissingFilesToArchive)
+	assert.Contains(t, stderr.String(), "cannot add files outside the current directory")
+}
+
+func TestArchiveErrors(t *testing.T) {
+	fsys := testingFs(t)
+	assert.Error(t, Archive(fsys, nil, false, []string{"mysite"}, Options{}))
+	assert.Error(t, Archive(fsys, io.Discard, false, []string{}, Options{}))
+	assert.Error(t, Archive(fsys, io.Discard, false, []string{"not-found"}, Options{}))
+}
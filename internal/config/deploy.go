@@ -0,0 +1,39 @@
+// Copyright © 2023 tsuru-client authors
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/afero"
+)
+
+// deployHashPath returns the path of the file holding the hash of the last
+// archive deployed to the app on the target.
+func deployHashPath(target, appName string) string {
+	return filepath.Join(ConfigPath, "deploy-cache", url.QueryEscape(target), url.QueryEscape(appName))
+}
+
+// GetLastDeployHash returns the hash of the last archive deployed to the app
+// on the target (empty when unknown).
+func GetLastDeployHash(fsys afero.Fs, target, appName string) string {
+	b, err := afero.ReadFile(fsys, deployHashPath(target, appName))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
+
+// SaveLastDeployHash saves the hash of the archive deployed to the app on the
+// target.
+func SaveLastDeployHash(fsys afero.Fs, target, appName, hash string) error {
+	path := deployHashPath(target, appName)
+	if err := fsys.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return afero.WriteFile(fsys, path, []byte(hash+"\n"), 0600)
+}
@@ -72,6 +72,33 @@ func getTargetLabel(fsys afero.Fs) (string, error) {
 
 }
 
+// findTargetLabel returns the label of target, which may be either a label or
+// the URL of a target.
+func findTargetLabel(fsys afero.Fs, target string) (string, error) {
+	targets, err := getTargets(fsys)
+	if err != nil {
+		return "", err
+	}
+	if _, ok := targets[target]; ok {
+		return target, nil
+	}
+	targetURL, err := GetTargetURL(fsys, target)
+	if err != nil {
+		return "", err
+	}
+	labels := make([]string, 0, len(targets))
+	for label := range targets {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	for _, label := range labels {
+		if u, _ := GetTargetURL(fsys, targets[label]); u == targetURL {
+			return label, nil
+		}
+	}
+	return "", fmt.Errorf("label for target %q not found", target)
+}
+
 // GetCurrentTargetFromFs returns the current target (from filesystem .tsuru/target)
 func GetCurrentTargetFromFs(fsys afero.Fs) (target string, err error) {
 	targetPath := filepath.Join(ConfigPath, "target")
@@ -0,0 +1,36 @@
+// Copyright © 2023 tsuru-client authors
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindTargetLabel(t *testing.T) {
+	fsys := afero.NewMemMapFs()
+	afero.WriteFile(fsys, filepath.Join(ConfigPath, "targets"), []byte("prod https://tsuru.example.com\nstg stg.example.com\n"), 0600)
+
+	for _, tc := range []struct {
+		target string
+		label  string
+	}{
+		{"prod", "prod"},
+		{"https://tsuru.example.com", "prod"},
+		{"stg", "stg"},
+		{"stg.example.com", "stg"},
+		{"http://stg.example.com", "stg"},
+	} {
+		label, err := findTargetLabel(fsys, tc.target)
+		assert.NoError(t, err, tc.target)
+		assert.Equal(t, tc.label, label, tc.target)
+	}
+
+	_, err := findTargetLabel(fsys, "http://unknown.example.com")
+	assert.EqualError(t, err, `label for target "http://unknown.example.com" not found`)
+}
@@ -21,7 +21,28 @@ func GetTokenFromFs(fsys afero.Fs) (string, error) {
 	if targetLabel, err := getTargetLabel(fsys); err == nil {
 		tokenPaths = append([]string{filepath.Join(ConfigPath, "token.d", targetLabel)}, tokenPaths...)
 	}
+	return readFirstToken(fsys, tokenPaths)
+}
+
+// GetTargetTokenFromFs returns the token saved for the given target (a label
+// or an URL). Unlike GetTokenFromFs, it never falls back to the token of the
+// last login, which may belong to another target.
+func GetTargetTokenFromFs(fsys afero.Fs, target string) (string, error) {
+	targetLabel, err := findTargetLabel(fsys, target)
+	if err != nil {
+		return "", fmt.Errorf("not logged in to target %q", target)
+	}
+	token, err := afero.ReadFile(fsys, filepath.Join(ConfigPath, "token.d", targetLabel))
+	if os.IsNotExist(err) {
+		return "", fmt.Errorf("not logged in to target %q", target)
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(token)), nil
+}
 
+func readFirstToken(fsys afero.Fs, tokenPaths []string) (string, error) {
 	var err error
 	for _, tokenPath := range tokenPaths {
 		var tkFile afero.File
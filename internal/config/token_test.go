@@ -0,0 +1,40 @@
+// Copyright © 2023 tsuru-client authors
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetTargetTokenFromFs(t *testing.T) {
+	fsys := afero.NewMemMapFs()
+	afero.WriteFile(fsys, filepath.Join(ConfigPath, "targets"), []byte("prod https://tsuru.example.com\nstg http://stg.example.com\n"), 0600)
+	afero.WriteFile(fsys, filepath.Join(ConfigPath, "token"), []byte("lasttoken\n"), 0600)
+	afero.WriteFile(fsys, filepath.Join(ConfigPath, "token.d", "prod"), []byte("prodtoken\n"), 0600)
+
+	token, err := GetTargetTokenFromFs(fsys, "prod")
+	assert.NoError(t, err)
+	assert.Equal(t, "prodtoken", token)
+
+	token, err = GetTargetTokenFromFs(fsys, "https://tsuru.example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, "prodtoken", token)
+}
+
+func TestGetTargetTokenFromFsNotLoggedIn(t *testing.T) {
+	fsys := afero.NewMemMapFs()
+	afero.WriteFile(fsys, filepath.Join(ConfigPath, "targets"), []byte("prod https://tsuru.example.com\nstg http://stg.example.com\n"), 0600)
+	afero.WriteFile(fsys, filepath.Join(ConfigPath, "token"), []byte("lasttoken\n"), 0600)
+
+	_, err := GetTargetTokenFromFs(fsys, "stg")
+	assert.EqualError(t, err, `not logged in to target "stg"`)
+
+	_, err = GetTargetTokenFromFs(fsys, "http://unknown.example.com")
+	assert.EqualError(t, err, `not logged in to target "http://unknown.example.com"`)
+}
@@ -49,15 +49,26 @@ type FakeExec struct {
 	OutStdout  string
 	OutErr     error
 	CalledOpts ExecuteOptions
+
+	// OutStdouts, when not empty, are the stdout of the next calls (one per
+	// call, in order). OutStdout is used after all of them are consumed.
+	OutStdouts []string
+	// CalledOptsList are the options of every call, in order.
+	CalledOptsList []ExecuteOptions
 }
 
 func (e *FakeExec) Command(opts ExecuteOptions) error {
+	stdout := e.OutStdout
+	if len(e.OutStdouts) > 0 {
+		stdout, e.OutStdouts = e.OutStdouts[0], e.OutStdouts[1:]
+	}
 	if opts.Stdout != nil {
-		fmt.Fprint(opts.Stdout, e.OutStdout)
+		fmt.Fprint(opts.Stdout, stdout)
 	}
 	if opts.Stderr != nil {
 		fmt.Fprint(opts.Stderr, e.OutStderr)
 	}
 	e.CalledOpts = opts
+	e.CalledOptsList = append(e.CalledOptsList, opts)
 	return e.OutErr
 }
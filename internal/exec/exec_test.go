@@ -37,6 +37,22 @@ func TestFakeExecCommand(t *testing.T) {
 		assert.Equal(t, fakeE.OutStdout, stdout.String())
 		assert.Equal(t, fakeE.OutStderr, stderr.String())
 	})
+
+	t.Run("fake exec with many outputs", func(t *testing.T) {
+		fakeE := FakeExec{
+			OutStdout:  "default output",
+			OutStdouts: []string{"first output", "second output"},
+		}
+		for _, expected := range []string{"first output", "second output", "default output"} {
+			stdout := bytes.Buffer{}
+			err := fakeE.Command(ExecuteOptions{Cmd: expected, Stdout: &stdout})
+			assert.NoError(t, err)
+			assert.Equal(t, expected, stdout.String())
+		}
+		assert.Len(t, fakeE.CalledOptsList, 3)
+		assert.Equal(t, "first output", fakeE.CalledOptsList[0].Cmd)
+		assert.Equal(t, "default output", fakeE.CalledOpts.Cmd)
+	})
 }
 
 func TestOsExec(t *testing.T) {
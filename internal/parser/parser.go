@@ -110,3 +110,18 @@ func SliceToMapFlags(flags []string) (map[string]string, error) {
 	}
 	return ret, nil
 }
+
+// BytesValue returns a human readable size (in powers of 1024) of n bytes.
+// eg: 512B, 1.5KiB, 300.0MiB
+func BytesValue(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -139,6 +139,25 @@ func TestMemoryValue(t *testing.T) {
 	}
 }
 
+func TestBytesValue(t *testing.T) {
+	t.Parallel()
+	for i, test := range []struct {
+		given    int64
+		expected string
+	}{
+		{0, "0B"},
+		{1023, "1023B"},
+		{1024, "1.0KiB"},
+		{1536, "1.5KiB"},
+		{314572800, "300.0MiB"},
+		{2147483648, "2.0GiB"},
+	} {
+		t.Run(fmt.Sprintf("test %d", i), func(t *testing.T) {
+			assert.Equal(t, test.expected, BytesValue(test.given))
+		})
+	}
+}
+
 func TestIntValue(t *testing.T) {
 	assert.Equal(t, "", IntValue(nil))
 	i := 1
@@ -62,6 +62,19 @@ func (tc *TsuruContext) SetToken(value string) {
 	tc.Viper.Set("token", value)
 }
 
+// WithTarget returns a copy of the context using another target, with its token.
+func (c *TsuruContext) WithTarget(targetURL, token string) *TsuruContext {
+	vip := viper.New()
+	for _, key := range c.Viper.AllKeys() {
+		vip.Set(key, c.Viper.Get(key))
+	}
+	newCtx := *c
+	newCtx.Viper = vip
+	newCtx.SetTargetURL(targetURL)
+	newCtx.SetToken(token)
+	return &newCtx
+}
+
 // Config is the tsuru client configuration
 func (c *TsuruContext) Config() *tsuru.Configuration {
 	cfg := tsuru.NewConfiguration()
@@ -70,7 +83,11 @@ func (c *TsuruContext) Config() *tsuru.Configuration {
 	}
 	cfg.BasePath = c.TargetURL()
 	cfg.UserAgent = c.UserAgent
-	cfg.HTTPClient.Transport = c.httpTransportWrapper(cfg.HTTPClient.Transport)
+	// copying the client, so the shared one (eg: http.DefaultClient) is not
+	// wrapped again on every call (nor changed concurrently)
+	httpClient := *cfg.HTTPClient
+	httpClient.Transport = c.httpTransportWrapper(httpClient.Transport)
+	cfg.HTTPClient = &httpClient
 	return cfg
 }
 
@@ -102,7 +119,7 @@ func DefaultTestingTsuruContextOptions(vip *viper.Viper) *TsuruContextOpts {
 
 		Stdout: &strings.Builder{},
 		Stderr: &strings.Builder{},
-		Stdin:  &FakeStdin{strings.NewReader("")},
+		Stdin:  &FakeStdin{Reader: strings.NewReader("")},
 	}
 }
 
@@ -128,6 +145,8 @@ var _ DescriptorReader = &FakeStdin{}
 
 type FakeStdin struct {
 	Reader io.Reader
+	// Terminal makes the fake stdin pass as a terminal
+	Terminal bool
 }
 
 func (f *FakeStdin) Read(p []byte) (n int, err error) {
@@ -136,3 +155,8 @@ func (f *FakeStdin) Read(p []byte) (n int, err error) {
 func (f *FakeStdin) Fd() uintptr {
 	return 0
 }
+
+// IsTerminal reports whether the fake stdin passes as a terminal.
+func (f *FakeStdin) IsTerminal() bool {
+	return f.Terminal
+}
@@ -0,0 +1,207 @@
+// Copyright © 2023 tsuru-client authors
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package watch re-runs read commands from time to time (the --watch flag),
+// showing what changed on their output.
+package watch
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tsuru/tsuru-client/v2/internal/tsuructx"
+	"github.com/tsuru/tsuru-client/v2/pkg/printer"
+	"golang.org/x/term"
+)
+
+// Annotation marks the commands that can be watched, as cobra annotation
+// (eg: Annotations: map[string]string{watch.Annotation: "true"}). Only read
+// commands, which don't change anything, should be watched.
+const Annotation = "watchable"
+
+// DefaultInterval is used by --watch without a value.
+const DefaultInterval = 2 * time.Second
+
+// AddFlag adds the persistent --watch[=interval] flag to cmd.
+func AddFlag(cmd *cobra.Command) {
+	cmd.PersistentFlags().Duration("watch", 0, "Re-run read commands every interval (eg: --watch, --watch=5s), showing what changed")
+	cmd.PersistentFlags().Lookup("watch").NoOptDefVal = DefaultInterval.String()
+}
+
+// SetupCommands makes the commands annotated with Annotation under root run
+// repeatedly when --watch is given. The other commands fail with --watch.
+func SetupCommands(root *cobra.Command, tsuruCtx *tsuructx.TsuruContext) {
+	for _, cmd := range root.Commands() {
+		SetupCommands(cmd, tsuruCtx)
+		if cmd.RunE == nil {
+			continue
+		}
+		run := cmd.RunE
+		if cmd.Annotations[Annotation] != "true" {
+			cmd.RunE = func(cmd *cobra.Command, args []string) error {
+				if cmd.Flags().Changed("watch") {
+					return fmt.Errorf("--watch is not supported by %q", cmd.CommandPath())
+				}
+				return run(cmd, args)
+			}
+			continue
+		}
+		cmd.RunE = func(cmd *cobra.Command, args []string) error {
+			interval, _ := cmd.Flags().GetDuration("watch")
+			if interval <= 0 {
+				return run(cmd, args)
+			}
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer stop()
+
+			stdout := tsuruCtx.Stdout
+			defer func() { tsuruCtx.Stdout = stdout }()
+			w := &Watcher{
+				Out:      stdout,
+				TTY:      isTerminal(stdout),
+				Colorify: printer.Colorify{DisableColors: tsuruCtx.Viper.IsSet("disable-colors")},
+				Interval: interval,
+				Title:    strings.Join(append([]string{cmd.CommandPath()}, args...), " "),
+			}
+			return w.Run(ctx, func(out io.Writer) error {
+				tsuruCtx.Stdout = out
+				return run(cmd, args)
+			})
+		}
+	}
+}
+
+func isTerminal(out io.Writer) bool {
+	if t, ok := out.(interface{ IsTerminal() bool }); ok {
+		return t.IsTerminal()
+	}
+	f, ok := out.(interface{ Fd() uintptr })
+	return ok && term.IsTerminal(int(f.Fd()))
+}
+
+// Watcher renders an output every Interval, until the context is done. On
+// terminals the screen is redrawn highlighting the lines that changed since
+// the last refresh (like watch -d), otherwise only the changes are written.
+type Watcher struct {
+	Out      io.Writer
+	TTY      bool
+	Colorify printer.Colorify
+	Interval time.Duration
+	Title    string
+
+	now  func() time.Time
+	last []string
+}
+
+// Run calls render every Interval, showing what it writes. Errors returned by
+// render are shown as part of the output, so a failed refresh doesn't stop
+// watching.
+func (w *Watcher) Run(ctx context.Context, render func(out io.Writer) error) error {
+	if w.now == nil {
+		w.now = time.Now
+	}
+	first := true
+	for {
+		var buf bytes.Buffer
+		if err := render(&buf); err != nil {
+			fmt.Fprintf(&buf, "Error: %v\n", err)
+		}
+		lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+		if w.TTY {
+			w.redraw(lines, first)
+		} else {
+			w.printChanges(lines, first)
+		}
+		w.last, first = lines, false
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(w.Interval):
+		}
+	}
+}
+
+func (w *Watcher) redraw(lines []string, first bool) {
+	fmt.Fprint(w.Out, "\033[H\033[2J")
+	fmt.Fprintf(w.Out, "Every %s: %s    %s\n\n", w.Interval, w.Title, w.now().Format("2006-01-02 15:04:05"))
+	for i, line := range lines {
+		if !first && (i >= len(w.last) || w.last[i] != line) {
+			line = w.Colorify.Colorfy(line, "white", "", "inverse")
+		}
+		fmt.Fprintln(w.Out, line)
+	}
+}
+
+func (w *Watcher) printChanges(lines []string, first bool) {
+	if first {
+		fmt.Fprintln(w.Out, strings.Join(lines, "\n"))
+		return
+	}
+	changes := diffLines(w.last, lines)
+	if len(changes) == 0 {
+		return
+	}
+	fmt.Fprintf(w.Out, "--- %s\n", w.now().Format("2006-01-02 15:04:05"))
+	for _, change := range changes {
+		if change.removed {
+			fmt.Fprintln(w.Out, w.Colorify.Colorfy("- "+change.line, "red", "", ""))
+		} else {
+			fmt.Fprintln(w.Out, w.Colorify.Colorfy("+ "+change.line, "green", "", ""))
+		}
+	}
+}
+
+type lineChange struct {
+	line    string
+	removed bool
+}
+
+// diffLines returns the lines removed from before and added on after, in
+// order, using their longest common subsequence.
+func diffLines(before, after []string) []lineChange {
+	lcs := make([][]int, len(before)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(after)+1)
+	}
+	for i := len(before) - 1; i >= 0; i-- {
+		for j := len(after) - 1; j >= 0; j-- {
+			if before[i] == after[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var changes []lineChange
+	i, j := 0, 0
+	for i < len(before) || j < len(after) {
+		switch {
+		case i < len(before) && j < len(after) && before[i] == after[j]:
+			i, j = i+1, j+1
+		case i < len(before) && (j == len(after) || lcs[i+1][j] >= lcs[i][j+1]):
+			changes = append(changes, lineChange{line: before[i], removed: true})
+			i++
+		default:
+			changes = append(changes, lineChange{line: after[j]})
+			j++
+		}
+	}
+	return changes
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
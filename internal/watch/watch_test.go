@@ -0,0 +1,127 @@
+// Copyright © 2023 tsuru-client authors
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package watch
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/tsuru/tsuru-client/v2/internal/tsuructx"
+	"github.com/tsuru/tsuru-client/v2/pkg/printer"
+)
+
+// renderOutputs returns a render func writing each output on a call, and
+// canceling ctx on the last one.
+func renderOutputs(cancel func(), outputs ...string) func(io.Writer) error {
+	n := 0
+	return func(out io.Writer) error {
+		output := outputs[n]
+		n++
+		if n == len(outputs) {
+			cancel()
+		}
+		if output == "" {
+			return fmt.Errorf("app not found")
+		}
+		_, err := io.WriteString(out, output)
+		return err
+	}
+}
+
+func fixedNow() time.Time {
+	return time.Date(2023, 6, 1, 10, 30, 0, 0, time.UTC)
+}
+
+func TestWatcherRunPrintsChanges(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var out strings.Builder
+	w := &Watcher{Out: &out, Colorify: printer.Colorify{DisableColors: true}, Interval: time.Millisecond, now: fixedNow}
+	err := w.Run(ctx, renderOutputs(cancel,
+		"App: myapp\nUnits: 1\nweb-1 started\n",
+		"App: myapp\nUnits: 1\nweb-1 started\n",
+		"App: myapp\nUnits: 2\nweb-1 started\nweb-2 starting\n",
+		"",
+	))
+	assert.NoError(t, err)
+	expected := `App: myapp
+Units: 1
+web-1 started
+--- 2023-06-01 10:30:00
+- Units: 1
++ Units: 2
++ web-2 starting
+--- 2023-06-01 10:30:00
+- App: myapp
+- Units: 2
+- web-1 started
+- web-2 starting
++ Error: app not found
+`
+	assert.Equal(t, expected, out.String())
+}
+
+func TestWatcherRunRedrawsOnTerminal(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var out strings.Builder
+	w := &Watcher{Out: &out, TTY: true, Interval: time.Millisecond, Title: "tsuru app info myapp", now: fixedNow}
+	err := w.Run(ctx, renderOutputs(cancel,
+		"Units: 1\nweb-1 started\n",
+		"Units: 1\nweb-1 stopped\n",
+	))
+	assert.NoError(t, err)
+	header := "\033[H\033[2JEvery 1ms: tsuru app info myapp    2023-06-01 10:30:00\n\n"
+	expected := header + "Units: 1\nweb-1 started\n" +
+		header + "Units: 1\n\033[7;37;10mweb-1 stopped\033[0m\n"
+	assert.Equal(t, expected, out.String())
+}
+
+func TestDiffLines(t *testing.T) {
+	changes := diffLines([]string{"a", "b", "c", "d"}, []string{"a", "c", "x", "d", "e"})
+	assert.Equal(t, []lineChange{
+		{line: "b", removed: true},
+		{line: "x"},
+		{line: "e"},
+	}, changes)
+	assert.Nil(t, diffLines([]string{"a"}, []string{"a"}))
+}
+
+func TestSetupCommands(t *testing.T) {
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	root := &cobra.Command{Use: "tsuru"}
+	AddFlag(root)
+	calls := map[string]int{}
+	for _, name := range []string{"info", "remove"} {
+		name := name
+		cmd := &cobra.Command{Use: name, RunE: func(cmd *cobra.Command, args []string) error {
+			calls[name]++
+			return nil
+		}}
+		if name == "info" {
+			cmd.Annotations = map[string]string{Annotation: "true"}
+		}
+		root.AddCommand(cmd)
+	}
+	SetupCommands(root, tsuruCtx)
+
+	root.SetArgs([]string{"info"})
+	assert.NoError(t, root.Execute())
+	assert.Equal(t, 1, calls["info"])
+
+	root.SetArgs([]string{"remove"})
+	assert.NoError(t, root.Execute())
+	assert.Equal(t, 1, calls["remove"])
+
+	// not annotated, so --watch is rejected
+	root.SetArgs([]string{"remove", "--watch"})
+	root.SilenceErrors, root.SilenceUsage = true, true
+	assert.EqualError(t, root.Execute(), `--watch is not supported by "tsuru remove"`)
+	assert.Equal(t, 1, calls["remove"])
+}
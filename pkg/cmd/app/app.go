@@ -5,11 +5,17 @@
 package app
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net"
 	"net/url"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/antihax/optional"
@@ -29,10 +35,21 @@ func NewAppCmd(tsuruCtx *tsuructx.TsuruContext) *cobra.Command {
 	}
 	appCmd.AddCommand(newAppInfoCmd(tsuruCtx))
 	appCmd.AddCommand(newAppCreateCmd(tsuruCtx))
+	appCmd.AddCommand(newAppUpdateCmd(tsuruCtx))
 	appCmd.AddCommand(newAppListCmd(tsuruCtx))
 	appCmd.AddCommand(newAppShellCmd(tsuruCtx))
 	appCmd.AddCommand(newAppLogCmd(tsuruCtx))
 	appCmd.AddCommand(newAppDeployCmd(tsuruCtx))
+	appCmd.AddCommand(newAppValidateCmd(tsuruCtx))
+	appCmd.AddCommand(newAppRemoveCmd(tsuruCtx))
+	appCmd.AddCommand(newAppStartCmd(tsuruCtx))
+	appCmd.AddCommand(newAppStopCmd(tsuruCtx))
+	appCmd.AddCommand(newAppRestartCmd(tsuruCtx))
+	appCmd.AddCommand(newAppRunCmd(tsuruCtx))
+	appCmd.AddCommand(newAppWaitCmd(tsuruCtx))
+	appCmd.AddCommand(newAppDiffCmd(tsuruCtx))
+	appCmd.AddCommand(newAppEnvCmd(tsuruCtx))
+	appCmd.AddCommand(newAppUnitCmd(tsuruCtx))
 	return appCmd
 }
 
@@ -311,3 +328,105 @@ func AppNameAndUnitIDFromArgsOrFlags(cmd *cobra.Command, args []string) (appName
 	}
 	return
 }
+
+// appNameFromArgsOrFlag returns the app name given either by the --app flag or
+// by the first argument.
+func appNameFromArgsOrFlag(cmd *cobra.Command, args []string) (string, error) {
+	appName := cmd.Flag("app").Value.String()
+	if appName != "" && len(args) > 0 {
+		return "", fmt.Errorf("either pass an app name as an argument or use the --app flag, not both")
+	}
+	if appName == "" && len(args) > 0 {
+		appName = args[0]
+	}
+	if appName == "" {
+		return "", fmt.Errorf("no app was provided. Please provide an app name or use the --app flag")
+	}
+	return appName, nil
+}
+
+// confirm asks the question and returns whether the user answered yes.
+func confirm(tsuruCtx *tsuructx.TsuruContext, question string) bool {
+	fmt.Fprintf(tsuruCtx.Stdout, "%s (y/N) ", question)
+	var answer string
+	fmt.Fscanln(tsuruCtx.Stdin, &answer)
+	answer = strings.ToLower(answer)
+	return answer == "y" || answer == "yes"
+}
+
+// prefixWriter writes every line to out prefixed by prefix. Lines are written
+// holding mu, so many prefixWriters can share the same out.
+type prefixWriter struct {
+	out    io.Writer
+	mu     *sync.Mutex
+	prefix string
+	buf    []byte
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		if err := w.writeLine(w.buf[:i+1]); err != nil {
+			return 0, err
+		}
+		w.buf = w.buf[i+1:]
+	}
+}
+
+// Flush writes the last line, when it doesn't end with a new line.
+func (w *prefixWriter) Flush() error {
+	if len(w.buf) == 0 {
+		return nil
+	}
+	line := append(w.buf, '\n')
+	w.buf = nil
+	return w.writeLine(line)
+}
+
+func (w *prefixWriter) writeLine(line []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, err := fmt.Fprintf(w.out, "%s%s", w.prefix, line)
+	return err
+}
+
+type jsonMessage struct {
+	Message string
+	Error   string
+}
+
+// streamOutput copies a tsuru streaming response into out, line by line, as it
+// arrives. Lines encoded as JSON messages ({"Message": "...", "Error": "..."})
+// are decoded. It returns the last non-empty line written, and the error sent
+// by the server (if any).
+func streamOutput(out io.Writer, body io.Reader) (lastLine string, err error) {
+	reader := bufio.NewReader(body)
+	for {
+		line, readErr := reader.ReadString('\n')
+		if len(line) > 0 {
+			var msg jsonMessage
+			if strings.HasPrefix(line, "{") && json.Unmarshal([]byte(line), &msg) == nil {
+				if msg.Error != "" {
+					return lastLine, errors.New(msg.Error)
+				}
+				line = msg.Message
+			}
+			fmt.Fprint(out, line)
+			for _, l := range strings.Split(line, "\n") {
+				if l = strings.TrimSpace(l); l != "" {
+					lastLine = l
+				}
+			}
+		}
+		if readErr == io.EOF {
+			return lastLine, nil
+		}
+		if readErr != nil {
+			return lastLine, readErr
+		}
+	}
+}
@@ -6,6 +6,8 @@ package app
 
 import (
 	"fmt"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/spf13/cobra"
@@ -56,3 +58,40 @@ func TestAppNameAndUnitIDFromArgsOrFlags(t *testing.T) {
 	}
 
 }
+
+func TestStreamOutput(t *testing.T) {
+	t.Parallel()
+	for i, test := range []struct {
+		body       string
+		expectOut  string
+		expectLast string
+		err        error
+	}{
+		{"", "", "", nil},
+		{"plain\ntext\n\nOK\n", "plain\ntext\n\nOK\n", "OK", nil},
+		{"no trailing newline", "no trailing newline", "no trailing newline", nil},
+		{`{"Message":"json\nmessage\n"}` + "\n" + `{"Message":"OK\n"}` + "\n", "json\nmessage\nOK\n", "OK", nil},
+		{`{"Message":"started\n"}` + "\n" + `{"Message":"","Error":"unit not found"}` + "\n", "started\n", "started", fmt.Errorf("unit not found")},
+	} {
+		t.Run(fmt.Sprintf("test %d", i), func(t *testing.T) {
+			out := strings.Builder{}
+			lastLine, err := streamOutput(&out, strings.NewReader(test.body))
+			assert.Equal(t, test.err, err)
+			assert.Equal(t, test.expectOut, out.String())
+			assert.Equal(t, test.expectLast, lastLine)
+		})
+	}
+}
+
+func TestPrefixWriter(t *testing.T) {
+	var mu sync.Mutex
+	out := strings.Builder{}
+	w1 := &prefixWriter{out: &out, mu: &mu, prefix: "[app1] "}
+	w2 := &prefixWriter{out: &out, mu: &mu, prefix: "[app2] "}
+	fmt.Fprint(w1, "first ")
+	fmt.Fprint(w2, "other\n")
+	fmt.Fprint(w1, "line\nsecond\nthird")
+	assert.NoError(t, w1.Flush())
+	assert.NoError(t, w2.Flush())
+	assert.Equal(t, "[app2] other\n[app1] first line\n[app1] second\n[app1] third\n", out.String())
+}
@@ -111,28 +111,12 @@ func appCreateRun(tsuruCtx *tsuructx.TsuruContext, cmd *cobra.Command, args []st
 		return fmt.Errorf("flag --platform and argument platform cannot be used at the same time")
 	}
 
-	v := url.Values{}
+	v, err := appFormValues(cmd)
+	if err != nil {
+		return err
+	}
 	v.Set("name", appName)
 	v.Set("platform", platform)
-	v.Set("description", cmd.Flag("description").Value.String())
-	v.Set("plan", cmd.Flag("plan").Value.String())
-	v.Set("router", cmd.Flag("router").Value.String())
-	v.Set("teamOwner", cmd.Flag("team").Value.String())
-	v.Set("pool", cmd.Flag("pool").Value.String())
-	if tags, err := cmd.Flags().GetStringArray("tag"); err == nil {
-		for _, tag := range tags {
-			v.Add("tag", tag)
-		}
-	}
-	if routerOpts, err := cmd.Flags().GetStringArray("router-opts"); err == nil {
-		routerOptsMap, err := parser.SliceToMapFlags(routerOpts)
-		if err != nil {
-			return err
-		}
-		for key, val := range routerOptsMap {
-			v.Add("routeropts."+key, val)
-		}
-	}
 
 	b := strings.NewReader(v.Encode())
 	request, err := tsuruCtx.NewRequest("POST", "/apps", b)
@@ -160,3 +144,36 @@ func appCreateRun(tsuruCtx *tsuructx.TsuruContext, cmd *cobra.Command, args []st
 	fmt.Fprintln(tsuruCtx.Stdout, "Use app info to check the status of the app and its units.")
 	return nil
 }
+
+// appFormValues returns the attributes of the app given by the flags of cmd,
+// as the form fields expected by the API when creating or updating an app.
+// Flags not defined on cmd are left out.
+func appFormValues(cmd *cobra.Command) (url.Values, error) {
+	v := url.Values{}
+	for flagName, field := range map[string]string{
+		"description": "description",
+		"plan":        "plan",
+		"router":      "router",
+		"team":        "teamOwner",
+		"pool":        "pool",
+	} {
+		if flag := cmd.Flag(flagName); flag != nil {
+			v.Set(field, flag.Value.String())
+		}
+	}
+	if tags, err := cmd.Flags().GetStringArray("tag"); err == nil {
+		for _, tag := range tags {
+			v.Add("tag", tag)
+		}
+	}
+	if routerOpts, err := cmd.Flags().GetStringArray("router-opts"); err == nil {
+		routerOptsMap, err := parser.SliceToMapFlags(routerOpts)
+		if err != nil {
+			return nil, err
+		}
+		for key, val := range routerOptsMap {
+			v.Add("routeropts."+key, val)
+		}
+	}
+	return v, nil
+}
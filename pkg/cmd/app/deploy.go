@@ -5,13 +5,35 @@
 package app
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"hash"
 	"io"
+	"mime/multipart"
 	"net/http"
 	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
 
+	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
+	"github.com/tsuru/tablecli"
+	"github.com/tsuru/tsuru-client/v2/internal/archiver"
+	"github.com/tsuru/tsuru-client/v2/internal/config"
+	"github.com/tsuru/tsuru-client/v2/internal/parser"
 	"github.com/tsuru/tsuru-client/v2/internal/tsuructx"
+	"github.com/tsuru/tsuru-client/v2/pkg/printer"
 )
 
 func newAppDeployCmd(tsuruCtx *tsuructx.TsuruContext) *cobra.Command {
@@ -20,6 +42,23 @@ func newAppDeployCmd(tsuruCtx *tsuructx.TsuruContext) *cobra.Command {
 		Short: "deploy the source code and/or configurations to the application on Tsuru",
 		Long: `Deploy the source code and/or configurations to the application on Tsuru.
 Files specified in the ".tsuruignore" file are skipped - similar to ".gitignore". It also honors ".dockerignore" file if deploying with container file (--dockerfile).
+
+When deploying with container file (--dockerfile) given a directory, the container
+file is guessed from it ("Dockerfile.<APP>", "Containerfile.<APP>", "Dockerfile.tsuru",
+"Containerfile.tsuru", "Dockerfile" or "Containerfile" - in this order). If no file
+or dir is given, the container file's directory is used as build context.
+
+The tsuru.yaml and Procfile of the files are validated before uploading them (see
+"tsuru app validate").
+
+Deploys of archives identical to the last one deployed to the app (from this
+machine) are skipped, unless --force is given. The --json summary shows them with
+the "skipped" status.
+
+Interrupting the client (Ctrl-C) while the deploy runs offers to cancel it on the
+server; interrupting it again exits right away. Without a terminal (eg: on CI),
+the deploy is canceled and the client exits. Deploys left running can be
+canceled with "tsuru app deploy cancel".
 `,
 		Example: `To deploy using app's platform build process (just sending source code and/or configurations):
   Uploading all files within the current directory
@@ -34,6 +73,16 @@ Files specified in the ".tsuruignore" file are skipped - similar to ".gitignore"
   Uploading specific files (ignoring their base directories)
     $ tsuru app deploy -a <APP> --files-only ./my-code/main.go ./tsuru_stuff/Procfile
 
+  Uploading the files of a git ref of the local repository (uncommitted changes are left out)
+    $ tsuru app deploy -a <APP> --git-ref v1.2.0 .
+
+To check which files would be uploaded (nothing is deployed):
+    $ tsuru app deploy -a <APP> --dry-run .
+
+To deploy the same files to many apps (the archive is built only once):
+    $ tsuru app deploy -a <APP1> -a <APP2> .
+    $ tsuru app deploy --tag <TAG> --parallel 8 .
+
 To deploy using a container image:
     $ tsuru app deploy -a <APP> --image registry.example.com/my-company/app:v42
 
@@ -43,6 +92,9 @@ To deploy using container file ("docker build" mode):
 
   Sending a specific container file and specific directory as container build context:
     $ tsuru app deploy -a <APP> --dockerfile ./Dockerfile.other ./other/
+
+  Passing build arguments and choosing a stage of a multi-stage container file:
+    $ tsuru app deploy -a <APP> --dockerfile . --build-arg GO_VERSION=1.20 --build-target production
 `,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return appDeployCmdRun(tsuruCtx, cmd, args)
@@ -50,27 +102,54 @@ To deploy using container file ("docker build" mode):
 		Args: cobra.MinimumNArgs(0),
 	}
 
-	appDeployCmd.Flags().StringP("app", "a", "", "The name of the app (may be passed as argument)")
+	appDeployCmd.Flags().StringArrayP("app", "a", nil, "The name of the app (may be passed as argument; may be used multiple times to deploy many apps)")
+	appDeployCmd.Flags().StringSliceP("tag", "g", nil, "Deploy to every app with the given tag. Can be used multiple times")
+	appDeployCmd.Flags().Int("parallel", 4, "The maximum number of apps deployed at the same time")
+	appDeployCmd.Flags().Int("retries", 3, "The number of times a failed upload is retried (sending the whole archive again)")
 	appDeployCmd.Flags().StringP("image", "i", "", "The image to deploy in app")
 	appDeployCmd.Flags().StringP("message", "m", "", "A message describing this deploy")
 	appDeployCmd.Flags().BoolP("files-only", "f", false, "Enables single file deployment into the root of the app's tree")
 	appDeployCmd.Flags().String("dockerfile", "", "Container file")
+	appDeployCmd.Flags().String("git-ref", "", "Deploy the files as they are on this git ref (commit, branch or tag), leaving out uncommitted changes. The commit is used as deploy message when --message is not given")
+	appDeployCmd.Flags().StringArray("build-arg", nil, "Build-time variable for the container file, in the form KEY=VALUE (may be used multiple times)")
+	appDeployCmd.Flags().String("build-target", "", "Target build stage of a multi-stage container file")
 	appDeployCmd.Flags().Bool("new-version", false, "Creates a new version for the current deployment while preserving existing versions")
 	appDeployCmd.Flags().Bool("override-old-versions", false, "Force replace all deployed versions by this new deploy")
+	appDeployCmd.Flags().Bool("json", false, "Show the deploy summary in JSON format (progress goes to stderr)")
+	appDeployCmd.Flags().Bool("skip-validation", false, "Deploy even when the validation of tsuru.yaml and Procfile fails (see \"tsuru app validate\")")
+	appDeployCmd.Flags().Bool("force", false, "Deploy even when the archive didn't change since the last deploy of the app")
+	appDeployCmd.Flags().Bool("dry-run", false, "Show the files and values that would be sent, without deploying")
+
+	appDeployCmd.AddCommand(newAppDeployListCmd(tsuruCtx))
+	appDeployCmd.AddCommand(newAppDeployRollbackCmd(tsuruCtx))
+	appDeployCmd.AddCommand(newAppDeployCancelCmd(tsuruCtx))
 	return appDeployCmd
 }
 
 func appDeployCmdRun(tsuruCtx *tsuructx.TsuruContext, cmd *cobra.Command, args []string) error {
-	appName := cmd.Flag("app").Value.String()
-	if appName == "" && len(args) > 0 {
-		appName = args[0]
+	appNames, _ := cmd.Flags().GetStringArray("app")
+	tags, _ := cmd.Flags().GetStringSlice("tag")
+	if len(appNames) > 0 && len(tags) > 0 {
+		return fmt.Errorf("you can't use --app and --tag at the same time")
+	}
+	if len(appNames) == 0 && len(tags) == 0 && len(args) > 0 {
+		appNames = args[:1]
 		args = args[1:]
 	}
 
-	if appName == "" {
+	if len(appNames) == 0 && len(tags) == 0 {
 		return fmt.Errorf("no app was provided. Please provide an app name")
 	}
 
+	if cmd.Flag("git-ref").Value.String() != "" {
+		if cmd.Flag("image").Value.String() != "" {
+			return fmt.Errorf("you can't deploy container image and git ref at the same time")
+		}
+		if cmd.Flag("dockerfile").Value.String() == "" && len(args) == 0 {
+			args = []string{"."}
+		}
+	}
+
 	if cmd.Flag("image").Value.String() == "" && cmd.Flag("dockerfile").Value.String() == "" && len(args) == 0 {
 		return fmt.Errorf("you should provide at least one file, Docker image name or Dockerfile to deploy")
 	}
@@ -83,13 +162,544 @@ func appDeployCmdRun(tsuruCtx *tsuructx.TsuruContext, cmd *cobra.Command, args [
 		return fmt.Errorf("you can't deploy container image and container file at same time")
 	}
 
+	if cmd.Flag("dockerfile").Value.String() == "" && (cmd.Flag("build-arg").Changed || cmd.Flag("build-target").Changed) {
+		return fmt.Errorf("flags --build-arg and --build-target can only be used with --dockerfile")
+	}
+
 	cmd.SilenceUsage = true
 
+	if len(tags) > 0 {
+		var err error
+		if appNames, err = appNamesFromQuery(tsuruCtx, appListQueryString(cmd, tsuruCtx)); err != nil {
+			return err
+		}
+		if len(appNames) == 0 {
+			return fmt.Errorf("no apps found with tag(s): %s", strings.Join(tags, ", "))
+		}
+	}
+
+	format := "table"
+	out := tsuruCtx.Stdout
+	if v, _ := cmd.Flags().GetBool("json"); v {
+		format = "json"
+		out = tsuruCtx.Stderr // keeping stdout for the summary only
+	}
+
+	debugWriter := io.Discard
+	debug := tsuruCtx.Verbosity() > 0 // e.g. --verbosity 2
+	if debug {
+		debugWriter = tsuruCtx.Stderr
+	}
+
+	containerFileApp := "" // app specific container files are only guessed deploying a single app
+	if len(appNames) == 1 {
+		containerFileApp = appNames[0]
+	}
+	values, archive, err := prepareDeploy(tsuruCtx, cmd, containerFileApp, args, debugWriter)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
+		return printDeployDryRun(tsuruCtx.Stdout, printer.FormatAs(format), strings.Join(appNames, ", "), values, archive)
+	}
+
+	switch {
+	case values.Get("image") != "":
+		fmt.Fprintln(out, "Deploying container image...")
+	case values.Get("dockerfile") != "":
+		fmt.Fprintln(out, "Deploying with Dockerfile...")
+	default:
+		fmt.Fprintln(out, "Deploying using app's platform...")
+	}
+
+	var writeArchive, streamArchive func(io.Writer) error
+	if archive != nil {
+		archive.opts.ContentHash = sha256.New()
+		archive.opts.Stats = &archiver.Stats{}
+		writeArchive = archive.Write
+		if len(appNames) == 1 { // many apps share an archive built once
+			streamArchive = archive.rewrite
+		}
+	}
+	body, err := newDeployBody(tsuruCtx.Fs, values, writeArchive, streamArchive)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	if archive != nil {
+		problems := validateAppFiles(archive.fsys, archive.opts.Stats, validateOptions{
+			filesOnly:   archive.filesOnly,
+			dockerfile:  values.Get("dockerfile") != "",
+			ignoreFiles: archive.opts.IgnoreFiles,
+		})
+		printValidationProblems(tsuruCtx.Stderr, problems)
+		if skip, _ := cmd.Flags().GetBool("skip-validation"); !skip && problems.Err() != nil {
+			return fmt.Errorf("%w, fix them or use --skip-validation to deploy anyway", problems.Err())
+		}
+	}
+
+	manyApps := len(appNames) > 1
+	var archiveHash string
+	var skipped []*deploySummary
+	if archive != nil {
+		archiveHash = deployHash(values, archive.opts.ContentHash)
+		if force, _ := cmd.Flags().GetBool("force"); !force {
+			appNames, skipped = skipUnchangedApps(tsuruCtx, appNames, archiveHash)
+			if len(appNames) == 0 {
+				return printSkippedDeploys(tsuruCtx.Stdout, printer.FormatAs(format), manyApps, skipped)
+			}
+		}
+	}
+
+	retries, _ := cmd.Flags().GetInt("retries")
+	ctx, abortUploads := context.WithCancel(context.Background())
+	defer abortUploads()
+	d := &deployer{
+		tsuruCtx: tsuruCtx,
+		body:     body,
+		retries:  retries,
+		ctx:      ctx,
+		events:   map[string]string{},
+		skipped:  skipped,
+
+		archiveHash: archiveHash,
+	}
+	stopInterrupts := d.handleInterrupts(abortUploads)
+	defer stopInterrupts()
+
+	if manyApps {
+		parallel, _ := cmd.Flags().GetInt("parallel")
+		return d.deployManyApps(out, printer.FormatAs(format), appNames, parallel)
+	}
+
+	summary, err := d.deploy(appNames[0], out, tsuruCtx.Stderr, isTerminalWriter(tsuruCtx.Stderr))
+	if err != nil {
+		return err
+	}
+	if err = summary.Print(tsuruCtx.Stdout, printer.FormatAs(format)); err != nil {
+		return err
+	}
+	return summary.Err()
+}
+
+// deployRetryBackoff is the time to wait before retrying a failed upload,
+// doubled on each retry.
+var deployRetryBackoff = time.Second
+
+// notifyInterrupt relays the interrupt signals (eg: Ctrl-C) to c.
+var notifyInterrupt = func(c chan<- os.Signal) {
+	signal.Notify(c, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+}
+
+// exitInterrupted exits the client when it can't wait for the deploys.
+var exitInterrupted = func() {
+	os.Exit(130)
+}
+
+// deployer sends the same deploy body to apps.
+type deployer struct {
+	tsuruCtx *tsuructx.TsuruContext
+	body     *deployBody
+	retries  int
+	ctx      context.Context // canceled to abort the uploads
+
+	mu     sync.Mutex
+	events map[string]string // event ID of the running deploys, by app
+
+	archiveHash string           // saved on successful deploys, see skipUnchangedApps
+	skipped     []*deploySummary // the apps left out by skipUnchangedApps
+}
+
+// handleInterrupts offers to cancel the running deploys when the client is
+// interrupted, instead of leaving them running on the server. Interrupts
+// before any deploy starts abort the uploads. Another interrupt while the
+// client asks whether to cancel exits right away. The returned function stops
+// handling interrupts.
+func (d *deployer) handleInterrupts(abortUploads func()) (stop func()) {
+	interrupt := make(chan os.Signal, 1)
+	notifyInterrupt(interrupt)
+	done := make(chan struct{})
+	go func() {
+		var handling chan struct{} // closed once the last interrupt is handled
+		for {
+			select {
+			case <-done:
+				return
+			case <-interrupt:
+			}
+			if handling != nil {
+				select {
+				case <-handling:
+				default:
+					fmt.Fprintln(d.tsuruCtx.Stderr, "\nInterrupted again, exiting. Running deploys may be canceled with \"tsuru app deploy cancel\".")
+					exitInterrupted()
+					continue
+				}
+			}
+			handling = make(chan struct{})
+			go func(handled chan struct{}) {
+				defer close(handled)
+				d.interrupted(abortUploads)
+			}(handling)
+		}
+	}()
+	return func() {
+		signal.Stop(interrupt)
+		close(done)
+	}
+}
+
+func (d *deployer) interrupted(abortUploads func()) {
+	d.mu.Lock()
+	var appNames, eventIDs []string
+	for appName, eventID := range d.events {
+		appNames = append(appNames, appName)
+		eventIDs = append(eventIDs, eventID)
+	}
+	d.mu.Unlock()
+
+	if len(eventIDs) == 0 {
+		fmt.Fprintln(d.tsuruCtx.Stderr, "Interrupted! Aborting the upload.")
+		abortUploads()
+		return
+	}
+	// without a terminal (eg: on CI) nobody answers, so the deploys are
+	// canceled and the client exits
+	interactive := isTerminal(d.tsuruCtx.Stdin)
+	if interactive {
+		colorify := printer.Colorify{DisableColors: d.tsuruCtx.Viper.IsSet("disable-colors")}
+		fmt.Fprintln(d.tsuruCtx.Stdout, colorify.Colorfy("Warning: the deploy is still RUNNING on the server!", "red", "", "bold"))
+		if !confirm(d.tsuruCtx, fmt.Sprintf("Do you want to cancel the deploy of %s?", strings.Join(appNames, ", "))) {
+			fmt.Fprintln(d.tsuruCtx.Stdout, "The deploy goes on.")
+			return
+		}
+	} else {
+		fmt.Fprintf(d.tsuruCtx.Stderr, "Interrupted! Canceling the deploy of %s.\n", strings.Join(appNames, ", "))
+	}
+	for i, eventID := range eventIDs {
+		if err := cancelEvent(d.tsuruCtx, eventID, defaultCancelReason); err != nil {
+			fmt.Fprintf(d.tsuruCtx.Stderr, "Error canceling the deploy of %s: %s\n", appNames[i], err)
+			continue
+		}
+		fmt.Fprintf(d.tsuruCtx.Stdout, "Cancellation of the deploy of %s requested.\n", appNames[i])
+	}
+	if !interactive {
+		exitInterrupted()
+	}
+}
+
+func (d *deployer) setRunningEvent(appName, eventID string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if eventID == "" {
+		delete(d.events, appName)
+		return
+	}
+	d.events[appName] = eventID
+}
+
+// deploy sends the deploy to the app, writing its output to out and the
+// upload progress to progressOut (as a progress bar when tty is set). Errors
+// sending the deploy are returned, while the deploy result is reported on the
+// summary.
+func (d *deployer) deploy(appName string, out, progressOut io.Writer, tty bool) (*deploySummary, error) {
+	startTime := time.Now()
+	httpResponse, err := d.upload(appName, progressOut, tty)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResponse.Body.Close()
+	if httpResponse.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("app %q not found", appName)
+	}
+	if httpResponse.StatusCode >= http.StatusBadRequest {
+		respBody, _ := io.ReadAll(httpResponse.Body)
+		return nil, fmt.Errorf("deploy failed: %s", strings.TrimSpace(string(respBody)))
+	}
+
+	summary := &deploySummary{
+		App:     appName,
+		EventID: httpResponse.Header.Get("X-Tsuru-Eventid"),
+		Status:  "succeeded",
+	}
+	if summary.EventID != "" {
+		d.setRunningEvent(appName, summary.EventID)
+		defer d.setRunningEvent(appName, "")
+	}
+	lastLine, err := streamOutput(out, httpResponse.Body)
+	if err != nil || lastLine != "OK" {
+		summary.Status = "failed"
+		if err != nil {
+			summary.Error = err.Error()
+		}
+	}
+	summary.duration = time.Since(startTime)
+	if deploy, errInfo := getDeployData(d.tsuruCtx, summary.EventID); errInfo == nil {
+		summary.Image = deploy.Image
+		summary.Version = deploy.Version
+		if deploy.Duration > 0 {
+			summary.duration = deploy.Duration
+		}
+		if deploy.Error != "" {
+			summary.Error = deploy.Error
+		}
+	}
+	summary.Duration = summary.duration.Round(time.Second).String()
+	if summary.Status == "succeeded" && d.archiveHash != "" {
+		if err = config.SaveLastDeployHash(d.tsuruCtx.Fs, d.tsuruCtx.TargetURL(), appName, d.archiveHash); err != nil {
+			fmt.Fprintf(progressOut, "WARNING: failed to save the hash of the deployed archive: %s\n", err)
+		}
+	}
+	return summary, nil
+}
+
+// deployHash identifies the archive deployed with the given form values
+// (but the message), to find out deploys that would change nothing.
+func deployHash(values url.Values, contentHash hash.Hash) string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		if k != "message" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	h := sha256.New()
+	for _, k := range keys {
+		fmt.Fprintf(h, "%s=%q\n", k, values[k])
+	}
+	h.Write(contentHash.Sum(nil))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// skipUnchangedApps returns the apps whose last deploy (from this client) was
+// not the same archive, warning about the others, which are returned as
+// skipped deploys.
+func skipUnchangedApps(tsuruCtx *tsuructx.TsuruContext, appNames []string, archiveHash string) ([]string, []*deploySummary) {
+	var changed []string
+	var skipped []*deploySummary
+	for _, appName := range appNames {
+		if config.GetLastDeployHash(tsuruCtx.Fs, tsuruCtx.TargetURL(), appName) == archiveHash {
+			fmt.Fprintf(tsuruCtx.Stderr, "WARNING: nothing changed since the last deploy of app %q, skipping it (use --force to deploy anyway).\n", appName)
+			skipped = append(skipped, &deploySummary{App: appName, Status: "skipped"})
+			continue
+		}
+		changed = append(changed, appName)
+	}
+	return changed, skipped
+}
+
+// printSkippedDeploys prints the summary of a deploy whose apps were all
+// skipped, which is only written as JSON: otherwise the warnings are enough.
+func printSkippedDeploys(out io.Writer, format printer.OutputType, manyApps bool, skipped []*deploySummary) error {
+	if format != printer.JSON {
+		return nil
+	}
+	if manyApps {
+		return printer.PrintPrettyJSON(out, skipped)
+	}
+	return skipped[0].Print(out, format)
+}
+
+// upload sends the deploy request, retrying (with backoff) when it fails
+// before the deploy starts. Each retry sends the whole body again, so it's
+// only done when no deploy of the app is running: a proxy may fail after
+// tsuru has accepted the deploy.
+func (d *deployer) upload(appName string, progressOut io.Writer, tty bool) (*http.Response, error) {
+	backoff := deployRetryBackoff
+	for attempt := 1; ; attempt++ {
+		if d.ctx.Err() != nil {
+			return nil, fmt.Errorf("deploy interrupted before it started")
+		}
+		httpResponse, err := d.uploadOnce(appName, progressOut, tty)
+		if err != nil && d.ctx.Err() != nil {
+			return nil, fmt.Errorf("deploy interrupted before it started")
+		}
+		var reason string
+		switch {
+		case err != nil:
+			reason = err.Error()
+		case httpResponse.StatusCode == http.StatusBadGateway,
+			httpResponse.StatusCode == http.StatusServiceUnavailable,
+			httpResponse.StatusCode == http.StatusGatewayTimeout:
+			reason = httpResponse.Status
+		}
+		if reason == "" || attempt > d.retries {
+			return httpResponse, err
+		}
+		if httpResponse != nil {
+			httpResponse.Body.Close()
+		}
+		if d.ctx.Err() != nil {
+			return nil, fmt.Errorf("deploy interrupted before it started")
+		}
+		events, err := runningDeployEvents(d.tsuruCtx, appName)
+		if err != nil {
+			return nil, fmt.Errorf("upload failed (%s), not retrying as it can't be checked whether the deploy started: %w", reason, err)
+		}
+		if len(events) > 0 {
+			return nil, fmt.Errorf("upload failed (%s), but a deploy of app %q is running (event %s), not retrying", reason, appName, events[0].UniqueID)
+		}
+		fmt.Fprintf(progressOut, "Upload failed (%s), retrying in %s (%d of %d)...\n", reason, backoff, attempt, d.retries)
+		select {
+		case <-d.ctx.Done():
+			return nil, fmt.Errorf("deploy interrupted before it started")
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (d *deployer) uploadOnce(appName string, progressOut io.Writer, tty bool) (*http.Response, error) {
+	body, err := d.body.open()
+	if err != nil {
+		return nil, err
+	}
+	if d.body.hasArchive() { // showing progress of archives only
+		body = newUploadProgress(progressOut, tty, d.body.size).Reader(body)
+	}
+	request, err := d.tsuruCtx.NewRequest("POST", "/apps/"+appName+"/deploy", body)
+	if err != nil {
+		body.Close()
+		return nil, err
+	}
+	request = request.WithContext(d.ctx)
+	request.ContentLength = d.body.size
+	request.Header.Set("Content-Type", d.body.contentType)
+	return d.tsuruCtx.RawHTTPClient().Do(request)
+}
+
+// deployManyApps sends the deploy to every app, at most parallel at a time,
+// prefixing the output of each app with its name.
+func (d *deployer) deployManyApps(out io.Writer, format printer.OutputType, appNames []string, parallel int) error {
+	if parallel < 1 {
+		parallel = 1
+	}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, parallel)
+	summaries := make([]*deploySummary, len(appNames))
+	for i, appName := range appNames {
+		wg.Add(1)
+		go func(i int, appName string) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			prefix := "[" + appName + "] "
+			appOut := &prefixWriter{out: out, mu: &mu, prefix: prefix}
+			progressOut := &prefixWriter{out: d.tsuruCtx.Stderr, mu: &mu, prefix: prefix}
+			summary, err := d.deploy(appName, appOut, progressOut, false)
+			appOut.Flush()
+			progressOut.Flush()
+			if err != nil {
+				summary = &deploySummary{App: appName, Status: "failed", Error: err.Error()}
+			}
+			summaries[i] = summary
+		}(i, appName)
+	}
+	wg.Wait()
+	summaries = append(summaries, d.skipped...)
+
+	var failed int
+	for _, summary := range summaries {
+		if summary.Status == "failed" {
+			failed++
+		}
+	}
+
+	if format == printer.JSON {
+		if err := printer.PrintPrettyJSON(d.tsuruCtx.Stdout, summaries); err != nil {
+			return err
+		}
+	} else {
+		table := tablecli.NewTable()
+		table.Headers = tablecli.Row([]string{"App", "Status", "Duration", "Version", "Error"})
+		for _, summary := range summaries {
+			version := ""
+			if summary.Version > 0 {
+				version = strconv.Itoa(summary.Version)
+			}
+			table.AddRow(tablecli.Row([]string{summary.App, summary.Status, summary.Duration, version, summary.Error}))
+		}
+		fmt.Fprintln(d.tsuruCtx.Stdout)
+		d.tsuruCtx.Stdout.Write(table.Bytes())
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("deploy failed for %d of %d apps", failed, len(summaries))
+	}
+	return nil
+}
+
+// appNamesFromQuery returns the names of the apps matching the app list query.
+func appNamesFromQuery(tsuruCtx *tsuructx.TsuruContext, qs url.Values) ([]string, error) {
+	qs.Set("simplified", "true")
+	request, err := tsuruCtx.NewRequest("GET", "/apps", nil)
+	if err != nil {
+		return nil, err
+	}
+	request.URL.RawQuery = qs.Encode()
+	httpResponse, err := tsuruCtx.RawHTTPClient().Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResponse.Body.Close()
+	if httpResponse.StatusCode == http.StatusNoContent {
+		return nil, nil
+	}
+	if httpResponse.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(httpResponse.Body)
+		return nil, fmt.Errorf("failed to list apps: %s", strings.TrimSpace(string(respBody)))
+	}
+	var apps []app
+	if err = json.NewDecoder(httpResponse.Body).Decode(&apps); err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(apps))
+	for _, a := range apps {
+		names = append(names, a.Name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// deployArchive is the archive of files sent on deploys.
+type deployArchive struct {
+	fsys      afero.Fs
+	filesOnly bool
+	paths     []string
+	opts      archiver.Options
+	cleanup   func() error // removes the files extracted from a git ref
+}
+
+func (a *deployArchive) Write(w io.Writer) error {
+	return archiver.Archive(a.fsys, w, a.filesOnly, a.paths, a.opts)
+}
+
+// rewrite writes the archive again, without repeating its warnings nor
+// filling again its stats and content hash.
+func (a *deployArchive) rewrite(w io.Writer) error {
+	opts := a.opts
+	opts.Stderr, opts.Stats, opts.ContentHash = nil, nil, nil
+	return archiver.Archive(a.fsys, w, a.filesOnly, a.paths, opts)
+}
+
+// Close removes the files extracted from a git ref, if any.
+func (a *deployArchive) Close() error {
+	if a == nil || a.cleanup == nil {
+		return nil
+	}
+	return a.cleanup()
+}
+
+// prepareDeploy returns the form values and the archive (nil when deploying a
+// container image) to be sent on deploys, according to the command flags.
+func prepareDeploy(tsuruCtx *tsuructx.TsuruContext, cmd *cobra.Command, appName string, args []string, debugWriter io.Writer) (url.Values, *deployArchive, error) {
 	values := url.Values{}
 	values.Set("origin", "app-deploy")
-	if cmd.Flag("image").Value.String() != "" {
-		values.Set("origin", "image")
-	}
 	if msg := cmd.Flag("message").Value.String(); msg != "" {
 		values.Set("message", msg)
 	}
@@ -100,26 +710,292 @@ func appDeployCmdRun(tsuruCtx *tsuructx.TsuruContext, cmd *cobra.Command, args [
 		values.Set("override-versions", "true")
 	}
 
-	requestReader, _ := io.Pipe()
-	request, err := tsuruCtx.NewRequest("POST", "/apps/"+appName+"/deploy", requestReader)
+	if image := cmd.Flag("image").Value.String(); image != "" {
+		values.Set("origin", "image")
+		values.Set("image", image)
+		return values, nil, nil
+	}
+
+	fsys := tsuruCtx.Fs
+	var cleanup func() error
+	if ref := cmd.Flag("git-ref").Value.String(); ref != "" {
+		commit, err := gitCommitFromRef(tsuruCtx.Executor, ref)
+		if err != nil {
+			return nil, nil, err
+		}
+		if fsys, cleanup, err = gitTree(tsuruCtx.Fs, tsuruCtx.Executor, tsuruCtx.Stderr, commit); err != nil {
+			return nil, nil, err
+		}
+		if values.Get("message") == "" {
+			values.Set("message", commit.SHA+" "+commit.Subject)
+		}
+	}
+
+	filesOnly, _ := cmd.Flags().GetBool("files-only")
+	archive := &deployArchive{
+		fsys:      fsys,
+		filesOnly: filesOnly,
+		paths:     args,
+		opts:      archiver.DefaultOptions(debugWriter),
+		cleanup:   cleanup,
+	}
+
+	if dockerfile := cmd.Flag("dockerfile").Value.String(); dockerfile != "" {
+		containerfile, paths, err := resolveContainerFile(fsys, appName, dockerfile, args)
+		if err != nil {
+			archive.Close()
+			return nil, nil, err
+		}
+		values.Set("dockerfile", containerfile)
+		if target := cmd.Flag("build-target").Value.String(); target != "" {
+			values.Set("target", target)
+		}
+		buildArgs, _ := cmd.Flags().GetStringArray("build-arg")
+		buildArgsMap, err := parser.SliceToMapFlags(buildArgs)
+		if err != nil {
+			archive.Close()
+			return nil, nil, err
+		}
+		for key, val := range buildArgsMap {
+			values.Set("buildargs."+key, val)
+		}
+		archive.paths = paths
+		archive.opts.IgnoreFiles = []string{dockerIgnoreFile(fsys, paths)}
+	}
+	return values, archive, nil
+}
+
+// resolveContainerFile returns the content of the container file and the paths
+// used as the build context. If path is a directory, the container file is
+// guessed from its content.
+func resolveContainerFile(fsys afero.Fs, appName, path string, files []string) (string, []string, error) {
+	fi, err := fsys.Stat(path)
 	if err != nil {
-		return err
+		return "", nil, fmt.Errorf("failed to stat the file %s: %w", path, err)
+	}
+	if fi.IsDir() {
+		path, err = guessContainerFile(fsys, appName, path)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to guess the container file (can you specify the container file passing --dockerfile ./path/to/Dockerfile?): %w", err)
+		}
+	} else if !fi.Mode().IsRegular() {
+		return "", nil, fmt.Errorf("invalid file type: %s", path)
+	}
+
+	containerfile, err := afero.ReadFile(fsys, path)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if len(files) == 0 { // no additional files set, using the container file dir
+		files = []string{filepath.Dir(path)}
+	}
+	return string(containerfile), files, nil
+}
+
+func guessContainerFile(fsys afero.Fs, appName, dir string) (string, error) {
+	validNames := []string{
+		"Dockerfile.tsuru",
+		"Containerfile.tsuru",
+		"Dockerfile",
+		"Containerfile",
+	}
+	if appName != "" {
+		validNames = append([]string{"Dockerfile." + appName, "Containerfile." + appName}, validNames...)
+	}
+	for _, name := range validNames {
+		path := filepath.Join(dir, name)
+		fi, err := fsys.Stat(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if fi.Mode().IsRegular() {
+			return path, nil
+		}
+	}
+	return "", errors.New("container file not found")
+}
+
+// dockerIgnoreFile returns the ".dockerignore" file at the root of the build context.
+func dockerIgnoreFile(fsys afero.Fs, paths []string) string {
+	if len(paths) == 1 {
+		if fi, err := fsys.Stat(paths[0]); err == nil && fi.IsDir() {
+			return filepath.Join(paths[0], ".dockerignore")
+		}
+	}
+	return ".dockerignore"
+}
+
+type deployDryRun struct {
+	App     string            `json:"app"`
+	Values  map[string]string `json:"values"`
+	Archive *archiver.Stats   `json:"archive,omitempty"`
+}
+
+// printDeployDryRun builds the archive (without sending it) and prints what
+// would be sent on the deploy.
+func printDeployDryRun(out io.Writer, format printer.OutputType, appName string, values url.Values, archive *deployArchive) error {
+	dryRun := &deployDryRun{App: appName, Values: map[string]string{}}
+	for k := range values {
+		dryRun.Values[k] = values.Get(k)
+	}
+	if archive != nil {
+		dryRun.Archive = &archiver.Stats{}
+		archive.opts.Stats = dryRun.Archive
+		if err := archive.Write(io.Discard); err != nil {
+			return err
+		}
+	}
+
+	if format == printer.JSON {
+		return printer.PrintPrettyJSON(out, dryRun)
+	}
+	return printer.PrintInfo(out, format, dryRun.printable(), nil)
+}
+
+func (d *deployDryRun) printable() printer.PrintableType {
+	p := printer.PrintableType{
+		SimpleFields: []printer.FieldType{{Name: "App", Value: d.App}},
+	}
+	valuesField := printer.DetailedFieldType{Name: "Values", Fields: []string{"Name", "Value"}}
+	keys := make([]string, 0, len(d.Values))
+	for k := range d.Values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		value := d.Values[k]
+		if lines := strings.Count(value, "\n"); lines > 0 {
+			value = fmt.Sprintf("(%d lines)", lines)
+		}
+		valuesField.Items = append(valuesField.Items, printer.ArrayItemType{k, value})
+	}
+	p.DetailedFields = append(p.DetailedFields, valuesField)
+
+	if d.Archive == nil {
+		return p
+	}
+	p.SimpleFields = append(p.SimpleFields,
+		printer.FieldType{Name: "Files", Value: fmt.Sprintf("%d added, %d ignored", d.Archive.Count("added"), len(d.Archive.Files)-d.Archive.Count("added"))},
+		printer.FieldType{Name: "Size", Value: parser.BytesValue(d.Archive.Size)},
+		printer.FieldType{Name: "Compressed size", Value: parser.BytesValue(d.Archive.CompressedSize)},
+	)
+	filesField := printer.DetailedFieldType{Name: "Archive", Fields: []string{"Path", "Name", "Size", "Status"}}
+	for _, f := range d.Archive.Files {
+		filesField.Items = append(filesField.Items, printer.ArrayItemType{f.Path, f.Name, parser.BytesValue(f.Size), f.Status})
+	}
+	p.DetailedFields = append(p.DetailedFields, filesField)
+	return p
+}
+
+type deployData struct {
+	ID          string
+	App         string
+	Timestamp   time.Time
+	Duration    time.Duration
+	Commit      string
+	Error       string
+	Image       string
+	Version     int
+	User        string
+	Origin      string
+	CanRollback bool
+	Message     string
+}
+
+func getDeployData(tsuruCtx *tsuructx.TsuruContext, eventID string) (*deployData, error) {
+	if eventID == "" {
+		return nil, fmt.Errorf("event ID not available")
+	}
+	request, err := tsuruCtx.NewRequest("GET", "/deploys/"+eventID, nil)
+	if err != nil {
+		return nil, err
 	}
 	httpResponse, err := tsuruCtx.RawHTTPClient().Do(request)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer httpResponse.Body.Close()
-	if httpResponse.StatusCode == http.StatusNotFound {
-		return fmt.Errorf("app %q not found", appName)
+	if httpResponse.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("deploy %q not found", eventID)
 	}
+	var deploy deployData
+	if err = json.NewDecoder(httpResponse.Body).Decode(&deploy); err != nil {
+		return nil, err
+	}
+	return &deploy, nil
+}
 
-	// ----
-	debugWriter := io.Discard
-	debug := tsuruCtx.Verbosity() > 0 // e.g. --verbosity 2
-	if debug {
-		debugWriter = tsuruCtx.Stderr
+type deploySummary struct {
+	App      string `json:"app"`
+	EventID  string `json:"eventID,omitempty"`
+	Status   string `json:"status"`
+	Duration string `json:"duration,omitempty"`
+	Image    string `json:"image,omitempty"`
+	Version  int    `json:"version,omitempty"`
+	Error    string `json:"error,omitempty"`
+
+	duration time.Duration
+}
+
+// Err returns the error of a failed deploy.
+func (s *deploySummary) Err() error {
+	if s.Status != "failed" {
+		return nil
+	}
+	if s.Error != "" {
+		return fmt.Errorf("deploy failed: %s", s.Error)
+	}
+	return fmt.Errorf("deploy failed")
+}
+
+func (s *deploySummary) Print(out io.Writer, format printer.OutputType) error {
+	if format == printer.JSON {
+		return printer.PrintPrettyJSON(out, s)
+	}
+
+	fmt.Fprintln(out)
+	if s.Status == "succeeded" {
+		fmt.Fprintf(out, "Deploy of app %q succeeded in %s\n", s.App, s.Duration)
+	} else {
+		fmt.Fprintf(out, "Deploy of app %q failed after %s\n", s.App, s.Duration)
+	}
+	if s.Image != "" {
+		fmt.Fprintf(out, "Image: %s\n", s.Image)
+	}
+	if s.Version > 0 {
+		fmt.Fprintf(out, "Version: %d\n", s.Version)
+	}
+	if s.EventID != "" {
+		fmt.Fprintf(out, "Event: %s\n", s.EventID)
 	}
-	_ = debugWriter
 	return nil
 }
+
+// writeDeployMultipart writes the form values and the archive (as "file")
+// into mw, closing it at the end.
+func writeDeployMultipart(mw *multipart.Writer, values url.Values, writeArchive func(io.Writer) error) error {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		for _, v := range values[k] {
+			if err := mw.WriteField(k, v); err != nil {
+				return err
+			}
+		}
+	}
+
+	f, err := mw.CreateFormFile("file", "archive.tar.gz")
+	if err != nil {
+		return err
+	}
+	if err = writeArchive(f); err != nil {
+		return err
+	}
+	return mw.Close()
+}
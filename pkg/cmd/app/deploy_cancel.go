@@ -0,0 +1,125 @@
+// Copyright © 2023 tsuru-client authors
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tsuru/go-tsuruclient/pkg/tsuru"
+	"github.com/tsuru/tsuru-client/v2/internal/tsuructx"
+)
+
+const defaultCancelReason = "Canceled on client."
+
+func newAppDeployCancelCmd(tsuruCtx *tsuructx.TsuruContext) *cobra.Command {
+	appDeployCancelCmd := &cobra.Command{
+		Use:   "cancel [APP]",
+		Short: "cancel the running deploy of an app",
+		Long: `Cancel the deploy of an app that is still running on the server (eg: the
+client was closed while deploying).`,
+		Example: `$ tsuru app deploy cancel myapp
+$ tsuru app deploy cancel -a myapp -y --reason "wrong branch"`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return appDeployCancelCmdRun(tsuruCtx, cmd, args)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return completeAppNames(tsuruCtx, cmd, args, toComplete)
+		},
+		Args: cobra.RangeArgs(0, 1),
+	}
+
+	appDeployCancelCmd.Flags().StringP("app", "a", "", "The name of the app (may be passed as argument)")
+	appDeployCancelCmd.Flags().BoolP("yes", "y", false, "Don't ask for confirmation")
+	appDeployCancelCmd.Flags().String("reason", defaultCancelReason, "The reason of the cancellation, recorded on the deploy event")
+	return appDeployCancelCmd
+}
+
+func appDeployCancelCmdRun(tsuruCtx *tsuructx.TsuruContext, cmd *cobra.Command, args []string) error {
+	appName, err := appNameFromArgsOrFlag(cmd, args)
+	if err != nil {
+		return err
+	}
+	cmd.SilenceUsage = true
+
+	events, err := runningDeployEvents(tsuruCtx, appName)
+	if err != nil {
+		return err
+	}
+	if len(events) == 0 {
+		return fmt.Errorf("app %q has no running deploy", appName)
+	}
+
+	reason := cmd.Flag("reason").Value.String()
+	for _, evt := range events {
+		question := fmt.Sprintf("Are you sure you want to cancel the deploy of app %q started by %s at %s?", appName, evt.Owner.Name, evt.StartTime.In(tsuruCtx.LocalTZ).Format("2006-01-02 15:04:05"))
+		if yes, _ := cmd.Flags().GetBool("yes"); !yes && !confirm(tsuruCtx, question) {
+			continue
+		}
+		if err = cancelEvent(tsuruCtx, evt.UniqueID, reason); err != nil {
+			return err
+		}
+		fmt.Fprintf(tsuruCtx.Stdout, "Cancellation of deploy %s successfully requested.\n", evt.UniqueID)
+	}
+	return nil
+}
+
+// deployEvent is a deploy event, as returned by /events.
+type deployEvent struct {
+	UniqueID  string
+	StartTime time.Time
+	Owner     struct {
+		Type string
+		Name string
+	}
+	Running bool
+}
+
+// runningDeployEvents returns the deploys of the app still running on the server.
+func runningDeployEvents(tsuruCtx *tsuructx.TsuruContext, appName string) ([]deployEvent, error) {
+	request, err := tsuruCtx.NewRequest("GET", "/1.1/events", nil)
+	if err != nil {
+		return nil, err
+	}
+	qs := url.Values{}
+	qs.Set("target.type", "app")
+	qs.Set("target.value", appName)
+	qs.Set("running", "true")
+	qs.Set("kindname", "app.deploy")
+	request.URL.RawQuery = qs.Encode()
+	httpResponse, err := tsuruCtx.RawHTTPClient().Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResponse.Body.Close()
+	if httpResponse.StatusCode == http.StatusNoContent {
+		return nil, nil
+	}
+	if httpResponse.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(httpResponse.Body)
+		return nil, fmt.Errorf("failed to list deploy events: %s", strings.TrimSpace(string(respBody)))
+	}
+	var events []deployEvent
+	if err = json.NewDecoder(httpResponse.Body).Decode(&events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// cancelEvent asks the server to cancel the running event.
+func cancelEvent(tsuruCtx *tsuructx.TsuruContext, eventID, reason string) error {
+	_, err := tsuruCtx.Client().EventApi.EventCancel(context.Background(), eventID, tsuru.EventCancelArgs{Reason: reason})
+	if err != nil {
+		return fmt.Errorf("failed to cancel event %s: %w", eventID, err)
+	}
+	return nil
+}
@@ -0,0 +1,242 @@
+// Copyright © 2023 tsuru-client authors
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tsuru/tsuru-client/v2/internal/tsuructx"
+)
+
+func TestAppDeployCancelRun(t *testing.T) {
+	var reason string
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/1.1/events":
+			assert.Equal(t, "app", r.URL.Query().Get("target.type"))
+			assert.Equal(t, "myapp", r.URL.Query().Get("target.value"))
+			assert.Equal(t, "true", r.URL.Query().Get("running"))
+			assert.Equal(t, "app.deploy", r.URL.Query().Get("kindname"))
+			fmt.Fprintln(w, `[{"UniqueID":"5aec54d93195b20001194951","StartTime":"2023-06-20T10:00:00Z","Owner":{"Type":"user","Name":"me@example.com"},"Running":true}]`)
+		case "/1.1/events/5aec54d93195b20001194951/cancel":
+			assert.Equal(t, "POST", r.Method)
+			var args map[string]string
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&args))
+			reason = args["reason"]
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+	tsuruCtx.Stdin = &tsuructx.FakeStdin{Reader: strings.NewReader("y\n")}
+
+	cmd := newAppDeployCancelCmd(tsuruCtx)
+	cmd.Flags().Parse([]string{"--reason", "wrong branch"})
+	err := appDeployCancelCmdRun(tsuruCtx, cmd, []string{"myapp"})
+	assert.NoError(t, err)
+	assert.Equal(t, "wrong branch", reason)
+	expected := `Are you sure you want to cancel the deploy of app "myapp" started by me@example.com at 2023-06-20 10:00:00? (y/N) Cancellation of deploy 5aec54d93195b20001194951 successfully requested.
+`
+	assert.Equal(t, expected, tsuruCtx.Stdout.(*strings.Builder).String())
+}
+
+func TestAppDeployCancelRunNotConfirmed(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+		fmt.Fprintln(w, `[{"UniqueID":"5aec54d93195b20001194951","StartTime":"2023-06-20T10:00:00Z","Owner":{"Type":"user","Name":"me@example.com"},"Running":true}]`)
+	}))
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+	tsuruCtx.Stdin = &tsuructx.FakeStdin{Reader: strings.NewReader("n\n")}
+
+	cmd := newAppDeployCancelCmd(tsuruCtx)
+	err := appDeployCancelCmdRun(tsuruCtx, cmd, []string{"myapp"})
+	assert.NoError(t, err)
+}
+
+func TestAppDeployCancelRunNoRunningDeploy(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+
+	cmd := newAppDeployCancelCmd(tsuruCtx)
+	cmd.Flags().Parse([]string{"-y"})
+	err := appDeployCancelCmdRun(tsuruCtx, cmd, []string{"myapp"})
+	assert.EqualError(t, err, `app "myapp" has no running deploy`)
+}
+
+// syncBuilder is a strings.Builder safe for concurrent writes.
+type syncBuilder struct {
+	mu sync.Mutex
+	sb strings.Builder
+}
+
+func (b *syncBuilder) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.sb.Write(p)
+}
+
+func (b *syncBuilder) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.sb.String()
+}
+
+// fakeInterrupts replaces the interrupt signals by the returned channel.
+func fakeInterrupts(t *testing.T) chan<- os.Signal {
+	interrupt := make(chan os.Signal, 1)
+	original := notifyInterrupt
+	notifyInterrupt = func(c chan<- os.Signal) {
+		go func() {
+			for sig := range interrupt {
+				c <- sig
+			}
+		}()
+	}
+	t.Cleanup(func() {
+		notifyInterrupt = original
+		close(interrupt)
+	})
+	return interrupt
+}
+
+func TestAppDeployRunInterrupted(t *testing.T) {
+	interrupt := fakeInterrupts(t)
+	canceled := make(chan string, 1)
+	stdout := &syncBuilder{}
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/events/evt123/cancel"):
+			var args map[string]string
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&args))
+			canceled <- args["reason"]
+		case strings.HasSuffix(r.URL.Path, "/apps/myapp/deploy"):
+			w.Header().Set("X-Tsuru-Eventid", "evt123")
+			fmt.Fprintln(w, "---- Building application image ----")
+			w.(http.Flusher).Flush()
+			assert.Eventually(t, func() bool {
+				return strings.Contains(stdout.String(), "Building application image")
+			}, 5*time.Second, 10*time.Millisecond)
+			interrupt <- os.Interrupt
+			select {
+			case reason := <-canceled:
+				assert.Equal(t, "Canceled on client.", reason)
+				fmt.Fprintln(w, "ERROR: deploy canceled by user action")
+			case <-time.After(5 * time.Second):
+				t.Errorf("deploy was not canceled")
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+	tsuruCtx.Stdout = stdout
+	tsuruCtx.Stdin = &tsuructx.FakeStdin{Reader: strings.NewReader("y\n"), Terminal: true}
+
+	appDeployCmd := newAppDeployCmd(tsuruCtx)
+	appDeployCmd.Flags().Parse([]string{"-i", "nginx:latest"})
+	err := appDeployCmdRun(tsuruCtx, appDeployCmd, []string{"myapp"})
+	assert.EqualError(t, err, "deploy failed")
+	assert.Contains(t, stdout.String(), "Warning: the deploy is still RUNNING on the server!")
+	assert.Contains(t, stdout.String(), "Do you want to cancel the deploy of myapp? (y/N) ")
+	assert.Contains(t, stdout.String(), "Cancellation of the deploy of myapp requested.")
+	assert.Contains(t, stdout.String(), "ERROR: deploy canceled by user action")
+}
+
+func TestAppDeployRunInterruptedBeforeStart(t *testing.T) {
+	interrupt := fakeInterrupts(t)
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		interrupt <- os.Interrupt
+		<-r.Context().Done()
+	}))
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+
+	appDeployCmd := newAppDeployCmd(tsuruCtx)
+	appDeployCmd.Flags().Parse([]string{"-i", "nginx:latest"})
+	err := appDeployCmdRun(tsuruCtx, appDeployCmd, []string{"myapp"})
+	assert.EqualError(t, err, "deploy interrupted before it started")
+	assert.Equal(t, "Interrupted! Aborting the upload.\n", tsuruCtx.Stderr.(*strings.Builder).String())
+}
+
+// fakeExit replaces exitInterrupted, returning a channel notified on exits.
+func fakeExit(t *testing.T) <-chan struct{} {
+	exited := make(chan struct{}, 2)
+	original := exitInterrupted
+	exitInterrupted = func() { exited <- struct{}{} }
+	t.Cleanup(func() { exitInterrupted = original })
+	return exited
+}
+
+func TestDeployerInterruptedWithoutTerminal(t *testing.T) {
+	interrupt := fakeInterrupts(t)
+	exited := fakeExit(t)
+	canceled := make(chan string, 1)
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.True(t, strings.HasSuffix(r.URL.Path, "/events/evt123/cancel"))
+		canceled <- r.URL.Path
+	}))
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+	tsuruCtx.Stdout = &syncBuilder{}
+	tsuruCtx.Stderr = &syncBuilder{}
+	d := &deployer{tsuruCtx: tsuruCtx, events: map[string]string{"myapp": "evt123"}}
+	stop := d.handleInterrupts(func() { t.Error("uploads aborted") })
+	defer stop()
+
+	interrupt <- os.Interrupt
+	select {
+	case <-exited:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the client didn't exit")
+	}
+	assert.Len(t, canceled, 1)
+	assert.Equal(t, "Interrupted! Canceling the deploy of myapp.\n", tsuruCtx.Stderr.(*syncBuilder).String())
+	assert.NotContains(t, tsuruCtx.Stdout.(*syncBuilder).String(), "(y/N)")
+}
+
+func TestDeployerInterruptedTwice(t *testing.T) {
+	interrupt := fakeInterrupts(t)
+	exited := fakeExit(t)
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	stdout := &syncBuilder{}
+	tsuruCtx.Stdout = stdout
+	tsuruCtx.Stderr = &syncBuilder{}
+	answer, answerWriter := io.Pipe()
+	defer answerWriter.Close()
+	tsuruCtx.Stdin = &tsuructx.FakeStdin{Reader: answer, Terminal: true}
+	d := &deployer{tsuruCtx: tsuruCtx, events: map[string]string{"myapp": "evt123"}}
+	stop := d.handleInterrupts(func() { t.Error("uploads aborted") })
+	defer stop()
+
+	interrupt <- os.Interrupt
+	assert.Eventually(t, func() bool {
+		return strings.Contains(stdout.String(), "Do you want to cancel the deploy of myapp? (y/N) ")
+	}, 5*time.Second, 10*time.Millisecond)
+	interrupt <- os.Interrupt
+	select {
+	case <-exited:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the client didn't exit")
+	}
+	assert.Contains(t, tsuruCtx.Stderr.(*syncBuilder).String(), "Interrupted again, exiting.")
+}
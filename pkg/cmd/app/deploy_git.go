@@ -0,0 +1,138 @@
+// Copyright © 2023 tsuru-client authors
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/afero"
+	"github.com/tsuru/tsuru-client/v2/internal/exec"
+)
+
+type gitCommit struct {
+	SHA     string
+	Subject string
+}
+
+// gitCommitFromRef returns the commit pointed by ref on the local repository.
+func gitCommitFromRef(executor exec.Executor, ref string) (*gitCommit, error) {
+	var stdout, stderr bytes.Buffer
+	err := executor.Command(exec.ExecuteOptions{
+		Cmd:    "git",
+		Args:   []string{"log", "-1", "--format=%H%n%s", ref, "--"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve git ref %q: %w: %s", ref, err, strings.TrimSpace(stderr.String()))
+	}
+	sha, subject, _ := strings.Cut(strings.TrimSpace(stdout.String()), "\n")
+	if sha == "" {
+		return nil, fmt.Errorf("failed to resolve git ref %q", ref)
+	}
+	return &gitCommit{SHA: sha, Subject: subject}, nil
+}
+
+// gitTree extracts the files of the current directory as they are on the given
+// commit (uncommitted files are left out) to a temporary directory of fsys, so
+// the repository is never held in memory. It returns the files, with the same
+// relative paths, and a function removing them.
+func gitTree(fsys afero.Fs, executor exec.Executor, stderr io.Writer, commit *gitCommit) (afero.Fs, func() error, error) {
+	dir, err := afero.TempDir(fsys, "", "tsuru-git-ref-")
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanup := func() error { return fsys.RemoveAll(dir) }
+	if err = extractGitArchive(fsys, dir, executor, stderr, commit); err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	return afero.NewBasePathFs(fsys, dir), cleanup, nil
+}
+
+func extractGitArchive(fsys afero.Fs, dir string, executor exec.Executor, stderr io.Writer, commit *gitCommit) error {
+	pipeReader, pipeWriter := io.Pipe()
+	var gitStderr bytes.Buffer
+	gitErr := make(chan error, 1)
+	go func() {
+		err := executor.Command(exec.ExecuteOptions{
+			Cmd:    "git",
+			Args:   []string{"archive", "--format=tar", commit.SHA},
+			Stdout: pipeWriter,
+			Stderr: &gitStderr,
+		})
+		if err != nil {
+			err = fmt.Errorf("failed to archive git commit %s: %w: %s", commit.SHA, err, strings.TrimSpace(gitStderr.String()))
+		}
+		pipeWriter.CloseWithError(err)
+		gitErr <- err
+	}()
+	defer pipeReader.Close()
+
+	tr := tar.NewReader(pipeReader)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			// git may still be writing the padding after the end of the archive
+			io.Copy(io.Discard, pipeReader)
+			return <-gitErr
+		}
+		if err != nil {
+			return err
+		}
+		name := filepath.FromSlash(strings.TrimSuffix(h.Name, "/"))
+		if !filepath.IsLocal(name) {
+			return fmt.Errorf("invalid file name %q on git commit %s", h.Name, commit.SHA)
+		}
+		path := filepath.Join(dir, name)
+		switch h.Typeflag {
+		case tar.TypeDir:
+			err = fsys.MkdirAll(path, h.FileInfo().Mode().Perm())
+		case tar.TypeReg:
+			err = writeGitFile(fsys, path, h, tr)
+		case tar.TypeSymlink:
+			err = writeGitSymlink(fsys, path, h, stderr)
+		case tar.TypeXGlobalHeader: // commit ID added by git archive
+		default:
+			fmt.Fprintf(stderr, "WARNING: skipping file %q due to unsupported file type on git ref.\n", h.Name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func writeGitFile(fsys afero.Fs, path string, h *tar.Header, r io.Reader) error {
+	if err := fsys.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := fsys.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, h.FileInfo().Mode().Perm())
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(f, r)
+	return err
+}
+
+// writeGitSymlink creates the symlink as it is on the commit, so it's archived
+// like the symlinks of the working tree.
+func writeGitSymlink(fsys afero.Fs, path string, h *tar.Header, stderr io.Writer) error {
+	linker, ok := fsys.(afero.Linker)
+	if !ok {
+		fmt.Fprintf(stderr, "WARNING: skipping symlink %q, symlinks are not supported by the filesystem.\n", h.Name)
+		return nil
+	}
+	if err := fsys.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return linker.SymlinkIfPossible(h.Linkname, path)
+}
@@ -0,0 +1,112 @@
+// Copyright © 2023 tsuru-client authors
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/tsuru/tsuru-client/v2/internal/exec"
+	"github.com/tsuru/tsuru-client/v2/internal/tsuructx"
+)
+
+// gitArchiveOutput returns a tarball like the ones written by "git archive".
+func gitArchiveOutput(t *testing.T, files map[string]string) string {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	assert.NoError(t, tw.WriteHeader(&tar.Header{Typeflag: tar.TypeXGlobalHeader, Name: "pax_global_header", PAXRecords: map[string]string{"comment": "0123456789abcdef"}}))
+	assert.NoError(t, tw.WriteHeader(&tar.Header{Typeflag: tar.TypeDir, Name: "mysite/", Mode: 0755}))
+	for name, content := range files {
+		assert.NoError(t, tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: name, Mode: 0644, Size: int64(len(content))}))
+		tw.Write([]byte(content))
+	}
+	assert.NoError(t, tw.Close())
+	return buf.String()
+}
+
+func TestAppDeployRunGitRef(t *testing.T) {
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	stdout := strings.Builder{}
+	tsuruCtx.Stdout = &stdout
+	executor := &exec.FakeExec{OutStdouts: []string{
+		"0123456789abcdef\nAdd the home page\n",
+		gitArchiveOutput(t, map[string]string{"mysite/index.html": "<html></html>", ".tsuruignore": "*.log\n", "mysite/debug.log": "committed log"}),
+	}}
+	tsuruCtx.Executor = executor
+	afero.WriteFile(tsuruCtx.Fs, "mysite/uncommitted.go", []byte("package main"), 0644)
+
+	appDeployCmd := newAppDeployCmd(tsuruCtx)
+	appDeployCmd.Flags().Parse([]string{"--git-ref", "v1.2.0", "--dry-run", "--json"})
+	err := appDeployCmdRun(tsuruCtx, appDeployCmd, []string{"myapp", "mysite"})
+	assert.NoError(t, err)
+
+	if assert.Len(t, executor.CalledOptsList, 2) {
+		assert.Equal(t, []string{"log", "-1", "--format=%H%n%s", "v1.2.0", "--"}, executor.CalledOptsList[0].Args)
+		assert.Equal(t, []string{"archive", "--format=tar", "0123456789abcdef"}, executor.CalledOptsList[1].Args)
+	}
+	var dryRun deployDryRun
+	assert.NoError(t, json.Unmarshal([]byte(stdout.String()), &dryRun))
+	assert.Equal(t, "0123456789abcdef Add the home page", dryRun.Values["message"])
+	if assert.NotNil(t, dryRun.Archive) {
+		files := []string{}
+		for _, f := range dryRun.Archive.Files {
+			files = append(files, f.Name+" "+f.Status)
+		}
+		assert.Equal(t, []string{"debug.log ignored", "index.html added"}, files)
+	}
+}
+
+func TestAppDeployRunGitRefSymlinks(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	assert.NoError(t, tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: "mysite/index.html", Mode: 0644, Size: 13}))
+	tw.Write([]byte("<html></html>"))
+	assert.NoError(t, tw.WriteHeader(&tar.Header{Typeflag: tar.TypeSymlink, Name: "mysite/home.html", Linkname: "index.html", Mode: 0777}))
+	assert.NoError(t, tw.WriteHeader(&tar.Header{Typeflag: tar.TypeFifo, Name: "mysite/fifo", Mode: 0644}))
+	assert.NoError(t, tw.Close())
+
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.Fs = afero.NewOsFs() // the memory filesystem has no symlinks
+	stdout := strings.Builder{}
+	tsuruCtx.Stdout = &stdout
+	tsuruCtx.Executor = &exec.FakeExec{OutStdouts: []string{"0123456789abcdef\nAdd the home page\n", buf.String()}}
+
+	appDeployCmd := newAppDeployCmd(tsuruCtx)
+	appDeployCmd.Flags().Parse([]string{"--git-ref", "v1.2.0", "--dry-run", "--json"})
+	err := appDeployCmdRun(tsuruCtx, appDeployCmd, []string{"myapp", "mysite"})
+	assert.NoError(t, err)
+
+	var dryRun deployDryRun
+	assert.NoError(t, json.Unmarshal([]byte(stdout.String()), &dryRun))
+	if assert.NotNil(t, dryRun.Archive) {
+		files := []string{}
+		for _, f := range dryRun.Archive.Files {
+			files = append(files, f.Name+" "+f.Status)
+		}
+		assert.Equal(t, []string{"home.html added", "index.html added"}, files)
+	}
+	assert.Equal(t, "WARNING: skipping file \"mysite/fifo\" due to unsupported file type on git ref.\n", tsuruCtx.Stderr.(*strings.Builder).String())
+}
+
+func TestAppDeployRunGitRefErrors(t *testing.T) {
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.Executor = &exec.FakeExec{OutStderr: "fatal: bad revision 'nope'", OutErr: fmt.Errorf("exit status 128")}
+
+	appDeployCmd := newAppDeployCmd(tsuruCtx)
+	appDeployCmd.Flags().Parse([]string{"--git-ref", "nope"})
+	err := appDeployCmdRun(tsuruCtx, appDeployCmd, []string{"myapp"})
+	assert.EqualError(t, err, `failed to resolve git ref "nope": exit status 128: fatal: bad revision 'nope'`)
+
+	appDeployCmd = newAppDeployCmd(tsuruCtx)
+	appDeployCmd.Flags().Parse([]string{"--git-ref", "main", "-i", "nginx:latest"})
+	err = appDeployCmdRun(tsuruCtx, appDeployCmd, []string{"myapp"})
+	assert.EqualError(t, err, "you can't deploy container image and git ref at the same time")
+}
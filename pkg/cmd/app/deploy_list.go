@@ -0,0 +1,146 @@
+// Copyright © 2023 tsuru-client authors
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tsuru/tablecli"
+	"github.com/tsuru/tsuru-client/v2/internal/tsuructx"
+	"github.com/tsuru/tsuru-client/v2/internal/watch"
+	"github.com/tsuru/tsuru-client/v2/pkg/printer"
+)
+
+func newAppDeployListCmd(tsuruCtx *tsuructx.TsuruContext) *cobra.Command {
+	appDeployListCmd := &cobra.Command{
+		Use:   "list [APP]",
+		Short: "list the deploys of an app",
+		Long: `List the last deploys of an app, newest first.
+Images marked with (*) can be used on "tsuru app deploy rollback".`,
+		Example: `$ tsuru app deploy list myapp
+$ tsuru app deploy list -a myapp --limit 20 -o json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return appDeployListCmdRun(tsuruCtx, cmd, args)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return completeAppNames(tsuruCtx, cmd, args, toComplete)
+		},
+		Args:        cobra.RangeArgs(0, 1),
+		Annotations: map[string]string{watch.Annotation: "true"},
+	}
+
+	appDeployListCmd.Flags().StringP("app", "a", "", "The name of the app (may be passed as argument)")
+	appDeployListCmd.Flags().IntP("limit", "l", 10, "The maximum number of deploys to show")
+	appDeployListCmd.Flags().StringP("output", "o", "table", "Output format: table, json or yaml")
+	return appDeployListCmd
+}
+
+func appDeployListCmdRun(tsuruCtx *tsuructx.TsuruContext, cmd *cobra.Command, args []string) error {
+	appName, err := appNameFromArgsOrFlag(cmd, args)
+	if err != nil {
+		return err
+	}
+	cmd.SilenceUsage = true
+
+	limit, _ := cmd.Flags().GetInt("limit")
+	deploys, err := getDeployList(tsuruCtx, appName, limit)
+	if err != nil {
+		return err
+	}
+
+	format := printer.FormatAs(cmd.Flag("output").Value.String())
+	if len(deploys) == 0 && format == printer.Table {
+		fmt.Fprintf(tsuruCtx.Stdout, "App %s has no deploy.\n", appName)
+		return nil
+	}
+	return printDeployList(tsuruCtx.Stdout, format, tsuruCtx.LocalTZ, tsuruCtx.Viper.IsSet("disable-colors"), deploys)
+}
+
+// getDeployList returns the last deploys of the app, newest first.
+func getDeployList(tsuruCtx *tsuructx.TsuruContext, appName string, limit int) ([]deployData, error) {
+	request, err := tsuruCtx.NewRequest("GET", "/deploys", nil)
+	if err != nil {
+		return nil, err
+	}
+	qs := url.Values{}
+	qs.Set("app", appName)
+	if limit > 0 {
+		qs.Set("limit", strconv.Itoa(limit))
+	}
+	request.URL.RawQuery = qs.Encode()
+	httpResponse, err := tsuruCtx.RawHTTPClient().Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResponse.Body.Close()
+	if httpResponse.StatusCode == http.StatusNoContent {
+		return nil, nil
+	}
+	if httpResponse.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("app %q not found", appName)
+	}
+	if httpResponse.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(httpResponse.Body)
+		return nil, fmt.Errorf("failed to list deploys: %s", respBody)
+	}
+
+	var deploys []deployData
+	if err = json.NewDecoder(httpResponse.Body).Decode(&deploys); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(deploys, func(i, j int) bool {
+		return deploys[i].Timestamp.After(deploys[j].Timestamp)
+	})
+	return deploys, nil
+}
+
+func printDeployList(out io.Writer, format printer.OutputType, localTZ *time.Location, noColor bool, deploys []deployData) error {
+	switch format {
+	case printer.JSON:
+		return printer.PrintPrettyJSON(out, deploys)
+	case printer.YAML:
+		return printer.PrintYAML(out, deploys)
+	}
+
+	colorify := printer.Colorify{DisableColors: noColor}
+	table := tablecli.NewTable()
+	table.Headers = tablecli.Row([]string{"Version", "Image (Rollback)", "Origin", "User", "Date (Duration)", "Error"})
+	for _, deploy := range deploys {
+		version := ""
+		if deploy.Version > 0 {
+			version = strconv.Itoa(deploy.Version)
+		}
+		image := deploy.Image
+		if deploy.CanRollback {
+			image += " (*)"
+		}
+		origin := deploy.Origin
+		if origin == "git" && deploy.Commit != "" {
+			origin = fmt.Sprintf("git (%.7s)", deploy.Commit)
+		}
+		date := fmt.Sprintf("%s (%s)", deploy.Timestamp.In(localTZ).Format("2006-01-02 15:04:05"), deploy.Duration.Round(time.Second))
+
+		row := []string{version, image, origin, deploy.User, date, deploy.Error}
+		if deploy.Error != "" {
+			for i, cell := range row {
+				if cell != "" {
+					row[i] = colorify.Colorfy(cell, "red", "", "")
+				}
+			}
+		}
+		table.AddRow(tablecli.Row(row))
+	}
+	table.LineSeparator = true
+	out.Write(table.Bytes())
+	return nil
+}
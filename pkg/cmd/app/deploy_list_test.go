@@ -0,0 +1,98 @@
+// Copyright © 2023 tsuru-client authors
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tsuru/tsuru-client/v2/internal/tsuructx"
+)
+
+const deployListResult = `[
+	{"ID":"1","App":"myapp","Timestamp":"2023-06-20T10:00:00Z","Duration":65000000000,"Image":"registry/app-myapp:v1","Version":1,"User":"me@example.com","Origin":"app-deploy","CanRollback":true},
+	{"ID":"2","App":"myapp","Timestamp":"2023-06-21T10:00:00Z","Duration":30000000000,"Image":"","Version":2,"User":"me@example.com","Origin":"git","Commit":"0123456789abcdef","Error":"build failed"},
+	{"ID":"3","App":"myapp","Timestamp":"2023-06-22T10:00:00Z","Duration":2000000000,"Image":"nginx:latest","Version":3,"User":"other@example.com","Origin":"image","CanRollback":true}
+]`
+
+func TestAppDeployListIsRegistered(t *testing.T) {
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	appCmd := NewAppCmd(tsuruCtx)
+	cmd, _, err := appCmd.Find([]string{"deploy", "list"})
+	assert.NoError(t, err)
+	assert.Equal(t, "list", cmd.Name())
+	cmd, _, err = appCmd.Find([]string{"deploy", "rollback"})
+	assert.NoError(t, err)
+	assert.Equal(t, "rollback", cmd.Name())
+}
+
+func TestAppDeployListRun(t *testing.T) {
+	expected := `+---------+---------------------------+---------------+-------------------+----------------------------+--------------+
+| Version | Image (Rollback)          | Origin        | User              | Date (Duration)            | Error        |
++---------+---------------------------+---------------+-------------------+----------------------------+--------------+
+| 3       | nginx:latest (*)          | image         | other@example.com | 2023-06-22 10:00:00 (2s)   |              |
++---------+---------------------------+---------------+-------------------+----------------------------+--------------+
+| 2       |                           | git (0123456) | me@example.com    | 2023-06-21 10:00:00 (30s)  | build failed |
++---------+---------------------------+---------------+-------------------+----------------------------+--------------+
+| 1       | registry/app-myapp:v1 (*) | app-deploy    | me@example.com    | 2023-06-20 10:00:00 (1m5s) |              |
++---------+---------------------------+---------------+-------------------+----------------------------+--------------+
+`
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/1.0/deploys", r.URL.Path)
+		assert.Equal(t, "myapp", r.URL.Query().Get("app"))
+		assert.Equal(t, "10", r.URL.Query().Get("limit"))
+		fmt.Fprintln(w, deployListResult)
+	}))
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+	tsuruCtx.Viper.Set("disable-colors", true)
+
+	cmd := newAppDeployListCmd(tsuruCtx)
+	err := appDeployListCmdRun(tsuruCtx, cmd, []string{"myapp"})
+	assert.NoError(t, err)
+	assert.Equal(t, expected, tsuruCtx.Stdout.(*strings.Builder).String())
+}
+
+func TestAppDeployListRunOutput(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "5", r.URL.Query().Get("limit"))
+		fmt.Fprintln(w, deployListResult)
+	}))
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+
+	cmd := newAppDeployListCmd(tsuruCtx)
+	cmd.Flags().Parse([]string{"-a", "myapp", "--limit", "5", "-o", "json"})
+	err := appDeployListCmdRun(tsuruCtx, cmd, []string{})
+	assert.NoError(t, err)
+
+	var deploys []deployData
+	assert.NoError(t, json.Unmarshal([]byte(tsuruCtx.Stdout.(*strings.Builder).String()), &deploys))
+	if assert.Len(t, deploys, 3) {
+		assert.Equal(t, []int{3, 2, 1}, []int{deploys[0].Version, deploys[1].Version, deploys[2].Version})
+		assert.Equal(t, "build failed", deploys[1].Error)
+	}
+}
+
+func TestAppDeployListRunNoDeploys(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+
+	cmd := newAppDeployListCmd(tsuruCtx)
+	err := appDeployListCmdRun(tsuruCtx, cmd, []string{"myapp"})
+	assert.NoError(t, err)
+	assert.Equal(t, "App myapp has no deploy.\n", tsuruCtx.Stdout.(*strings.Builder).String())
+
+	err = appDeployListCmdRun(tsuruCtx, cmd, []string{})
+	assert.EqualError(t, err, "no app was provided. Please provide an app name or use the --app flag")
+}
@@ -0,0 +1,134 @@
+// Copyright © 2023 tsuru-client authors
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/tsuru/tsuru-client/v2/internal/tsuructx"
+)
+
+func newAppDeployRollbackCmd(tsuruCtx *tsuructx.TsuruContext) *cobra.Command {
+	appDeployRollbackCmd := &cobra.Command{
+		Use:   "rollback APP [VERSION|IMAGE]",
+		Short: "rollback the app to a previous deploy",
+		Long: `Deploy again a previous version (or image) of the app.
+When neither version nor image is given, the deploys available for rollback are
+listed to choose from.`,
+		Example: `$ tsuru app deploy rollback myapp 3
+$ tsuru app deploy rollback -a myapp registry.example.com/tsuru/app-myapp:v3
+$ tsuru app deploy rollback myapp`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return appDeployRollbackCmdRun(tsuruCtx, cmd, args)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return completeAppNames(tsuruCtx, cmd, args, toComplete)
+		},
+		Args: cobra.RangeArgs(0, 2),
+	}
+
+	appDeployRollbackCmd.Flags().StringP("app", "a", "", "The name of the app (may be passed as argument)")
+	appDeployRollbackCmd.Flags().BoolP("yes", "y", false, "Don't ask for confirmation")
+	appDeployRollbackCmd.Flags().Bool("new-version", false, "Creates a new version for the rollback while preserving existing versions")
+	appDeployRollbackCmd.Flags().Bool("override-old-versions", false, "Force replace all deployed versions by the rollback")
+	return appDeployRollbackCmd
+}
+
+func appDeployRollbackCmdRun(tsuruCtx *tsuructx.TsuruContext, cmd *cobra.Command, args []string) error {
+	appName := cmd.Flag("app").Value.String()
+	if appName == "" && len(args) > 0 {
+		appName = args[0]
+		args = args[1:]
+	}
+	if appName == "" {
+		return fmt.Errorf("no app was provided. Please provide an app name or use the --app flag")
+	}
+	if len(args) > 1 {
+		return fmt.Errorf("too many arguments")
+	}
+	cmd.SilenceUsage = true
+
+	var image string
+	if len(args) > 0 {
+		image = args[0]
+		if yes, _ := cmd.Flags().GetBool("yes"); !yes && !confirm(tsuruCtx, fmt.Sprintf("Are you sure you want to rollback app %q to %q?", appName, image)) {
+			return nil
+		}
+	} else {
+		var err error
+		if image, err = pickRollbackImage(tsuruCtx, appName); err != nil {
+			return err
+		}
+	}
+
+	values := url.Values{}
+	values.Set("origin", "rollback")
+	values.Set("image", image)
+	if newV, _ := cmd.Flags().GetBool("new-version"); newV {
+		values.Set("new-version", "true")
+	}
+	if overrideV, _ := cmd.Flags().GetBool("override-old-versions"); overrideV {
+		values.Set("override-versions", "true")
+	}
+
+	request, err := tsuruCtx.NewRequest("POST", "/apps/"+appName+"/deploy/rollback", strings.NewReader(values.Encode()))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	httpResponse, err := tsuruCtx.RawHTTPClient().Do(request)
+	if err != nil {
+		return err
+	}
+	defer httpResponse.Body.Close()
+	if httpResponse.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("app %q not found", appName)
+	}
+	if httpResponse.StatusCode >= http.StatusBadRequest {
+		respBody, _ := io.ReadAll(httpResponse.Body)
+		return fmt.Errorf("rollback failed: %s", strings.TrimSpace(string(respBody)))
+	}
+	if _, err = streamOutput(tsuruCtx.Stdout, httpResponse.Body); err != nil {
+		return fmt.Errorf("rollback failed: %w", err)
+	}
+	return nil
+}
+
+// pickRollbackImage lists the deploys available for rollback and asks the
+// user to choose one of them, returning its image.
+func pickRollbackImage(tsuruCtx *tsuructx.TsuruContext, appName string) (string, error) {
+	deploys, err := getDeployList(tsuruCtx, appName, 0)
+	if err != nil {
+		return "", err
+	}
+	var options []deployData
+	for _, deploy := range deploys {
+		if deploy.CanRollback && deploy.Image != "" {
+			options = append(options, deploy)
+		}
+	}
+	if len(options) == 0 {
+		return "", fmt.Errorf("app %q has no deploy available for rollback", appName)
+	}
+
+	fmt.Fprintf(tsuruCtx.Stdout, "Deploys available for rollback of app %q:\n", appName)
+	for i, deploy := range options {
+		fmt.Fprintf(tsuruCtx.Stdout, "  [%d] version %d: %s (%s)\n", i+1, deploy.Version, deploy.Image, deploy.Timestamp.In(tsuruCtx.LocalTZ).Format("2006-01-02 15:04:05"))
+	}
+	fmt.Fprintf(tsuruCtx.Stdout, "Choose a deploy [1-%d]: ", len(options))
+	var answer string
+	fmt.Fscanln(tsuruCtx.Stdin, &answer)
+	choice, err := strconv.Atoi(answer)
+	if err != nil || choice < 1 || choice > len(options) {
+		return "", fmt.Errorf("invalid choice %q", answer)
+	}
+	return options[choice-1].Image, nil
+}
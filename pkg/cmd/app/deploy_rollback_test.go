@@ -0,0 +1,92 @@
+// Copyright © 2023 tsuru-client authors
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tsuru/tsuru-client/v2/internal/tsuructx"
+)
+
+func TestAppDeployRollbackRun(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/1.0/apps/myapp/deploy/rollback", r.URL.Path)
+		assert.NoError(t, r.ParseForm())
+		assert.Equal(t, "rollback", r.FormValue("origin"))
+		assert.Equal(t, "3", r.FormValue("image"))
+		assert.Equal(t, "true", r.FormValue("new-version"))
+		fmt.Fprintln(w, `{"Message":"rolling back\n"}`)
+		fmt.Fprintln(w, `{"Message":"OK\n"}`)
+	}))
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+
+	cmd := newAppDeployRollbackCmd(tsuruCtx)
+	cmd.Flags().Parse([]string{"-y", "--new-version"})
+	err := appDeployRollbackCmdRun(tsuruCtx, cmd, []string{"myapp", "3"})
+	assert.NoError(t, err)
+	assert.Equal(t, "rolling back\nOK\n", tsuruCtx.Stdout.(*strings.Builder).String())
+}
+
+func TestAppDeployRollbackRunNotConfirmed(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+	}))
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+	tsuruCtx.Stdin = &tsuructx.FakeStdin{Reader: strings.NewReader("n\n")}
+
+	cmd := newAppDeployRollbackCmd(tsuruCtx)
+	cmd.Flags().Parse([]string{"-a", "myapp"})
+	err := appDeployRollbackCmdRun(tsuruCtx, cmd, []string{"nginx:latest"})
+	assert.NoError(t, err)
+	assert.Equal(t, `Are you sure you want to rollback app "myapp" to "nginx:latest"? (y/N) `, tsuruCtx.Stdout.(*strings.Builder).String())
+}
+
+func TestAppDeployRollbackRunPicker(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/1.0/deploys" {
+			fmt.Fprintln(w, deployListResult)
+			return
+		}
+		assert.Equal(t, "/1.0/apps/myapp/deploy/rollback", r.URL.Path)
+		assert.Equal(t, "registry/app-myapp:v1", r.FormValue("image"))
+		fmt.Fprintln(w, `{"Message":"OK\n"}`)
+	}))
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+	tsuruCtx.Stdin = &tsuructx.FakeStdin{Reader: strings.NewReader("2\n")}
+
+	cmd := newAppDeployRollbackCmd(tsuruCtx)
+	err := appDeployRollbackCmdRun(tsuruCtx, cmd, []string{"myapp"})
+	assert.NoError(t, err)
+	assert.Equal(t, `Deploys available for rollback of app "myapp":
+  [1] version 3: nginx:latest (2023-06-22 10:00:00)
+  [2] version 1: registry/app-myapp:v1 (2023-06-20 10:00:00)
+Choose a deploy [1-2]: OK
+`, tsuruCtx.Stdout.(*strings.Builder).String())
+
+	tsuruCtx.Stdin = &tsuructx.FakeStdin{Reader: strings.NewReader("5\n")}
+	err = appDeployRollbackCmdRun(tsuruCtx, cmd, []string{"myapp"})
+	assert.EqualError(t, err, `invalid choice "5"`)
+}
+
+func TestAppDeployRollbackRunFailed(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"Message":"","Error":"the selected version is disabled for rollback"}`)
+	}))
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+
+	cmd := newAppDeployRollbackCmd(tsuruCtx)
+	cmd.Flags().Parse([]string{"-y"})
+	err := appDeployRollbackCmdRun(tsuruCtx, cmd, []string{"myapp", "2"})
+	assert.EqualError(t, err, "rollback failed: the selected version is disabled for rollback")
+}
@@ -5,9 +5,22 @@
 package app
 
 import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
 	"github.com/tsuru/tsuru-client/v2/internal/tsuructx"
 )
@@ -36,3 +49,520 @@ func TestAppDeployIsRegistered(t *testing.T) {
 	}
 	assert.True(t, found, "subcommand deploy not registered in appCmd")
 }
+
+func TestAppDeployRunUploadsArchive(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.True(t, strings.HasSuffix(r.URL.Path, "/apps/myapp/deploy"))
+		assert.NoError(t, r.ParseMultipartForm(1024*1024))
+		assert.Equal(t, "app-deploy", r.FormValue("origin"))
+		assert.Equal(t, "my deploy", r.FormValue("message"))
+		assert.Equal(t, "true", r.FormValue("new-version"))
+		assert.Equal(t, "", r.FormValue("override-versions"))
+
+		file, _, err := r.FormFile("file")
+		assert.NoError(t, err)
+		zr, err := gzip.NewReader(file)
+		assert.NoError(t, err)
+		tr := tar.NewReader(zr)
+		names := []string{}
+		for h, err := tr.Next(); err == nil; h, err = tr.Next() {
+			names = append(names, h.Name)
+		}
+		sort.Strings(names)
+		assert.Equal(t, []string{"Procfile", "main.go"}, names)
+		fmt.Fprintln(w, "OK")
+	}))
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+	afero.WriteFile(tsuruCtx.Fs, "mysite/main.go", []byte("package main"), 0644)
+	afero.WriteFile(tsuruCtx.Fs, "mysite/Procfile", []byte("web: ./main"), 0644)
+	afero.WriteFile(tsuruCtx.Fs, "mysite/debug.log", []byte("not deployed"), 0644)
+	afero.WriteFile(tsuruCtx.Fs, ".tsuruignore", []byte("*.log"), 0644)
+
+	appDeployCmd := newAppDeployCmd(tsuruCtx)
+	appDeployCmd.Flags().Parse([]string{"--app", "myapp", "-m", "my deploy", "--new-version"})
+	err := appDeployCmdRun(tsuruCtx, appDeployCmd, []string{"mysite"})
+	assert.NoError(t, err)
+}
+
+func TestAppDeployRunImage(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/x-www-form-urlencoded", r.Header.Get("Content-Type"))
+		assert.Equal(t, "image", r.FormValue("origin"))
+		assert.Equal(t, "registry.example.com/app:v42", r.FormValue("image"))
+		assert.Equal(t, "true", r.FormValue("override-versions"))
+		fmt.Fprintln(w, "OK")
+	}))
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+
+	appDeployCmd := newAppDeployCmd(tsuruCtx)
+	appDeployCmd.Flags().Parse([]string{"-i", "registry.example.com/app:v42", "--override-old-versions"})
+	err := appDeployCmdRun(tsuruCtx, appDeployCmd, []string{"myapp"})
+	assert.NoError(t, err)
+}
+
+func TestAppDeployRunMissingFiles(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+	}))
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+
+	appDeployCmd := newAppDeployCmd(tsuruCtx)
+	err := appDeployCmdRun(tsuruCtx, appDeployCmd, []string{"myapp", "not-found"})
+	assert.Error(t, err)
+}
+
+func TestAppDeployRunStreamsOutputAndSummary(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/deploys/evt123") {
+			fmt.Fprintln(w, `{"ID":"evt123","App":"myapp","Duration":62000000000,"Image":"registry.example.com/tsuru/app-myapp:v3","Version":3}`)
+			return
+		}
+		w.Header().Set("X-Tsuru-Eventid", "evt123")
+		fmt.Fprintln(w, "---- Building application image ----")
+		fmt.Fprintln(w, `{"Message":" ---> Step 1/3\n"}`)
+		fmt.Fprintln(w, "\nOK")
+	}))
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+
+	appDeployCmd := newAppDeployCmd(tsuruCtx)
+	appDeployCmd.Flags().Parse([]string{"-i", "nginx:latest"})
+	err := appDeployCmdRun(tsuruCtx, appDeployCmd, []string{"myapp"})
+	assert.NoError(t, err)
+	expected := `Deploying container image...
+---- Building application image ----
+ ---> Step 1/3
+
+OK
+
+Deploy of app "myapp" succeeded in 1m2s
+Image: registry.example.com/tsuru/app-myapp:v3
+Version: 3
+Event: evt123
+`
+	assert.Equal(t, expected, tsuruCtx.Stdout.(*strings.Builder).String())
+}
+
+func TestAppDeployRunFailed(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/deploys/evt123") {
+			fmt.Fprintln(w, `{"ID":"evt123","App":"myapp","Duration":5000000000,"Error":"exit status 1"}`)
+			return
+		}
+		w.Header().Set("X-Tsuru-Eventid", "evt123")
+		fmt.Fprintln(w, "---- Building application image ----")
+		fmt.Fprintln(w, "ERROR: exit status 1")
+	}))
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+
+	appDeployCmd := newAppDeployCmd(tsuruCtx)
+	appDeployCmd.Flags().Parse([]string{"-i", "nginx:latest"})
+	err := appDeployCmdRun(tsuruCtx, appDeployCmd, []string{"myapp"})
+	assert.EqualError(t, err, "deploy failed: exit status 1")
+	assert.Contains(t, tsuruCtx.Stdout.(*strings.Builder).String(), `Deploy of app "myapp" failed after 5s`)
+}
+
+func TestAppDeployRunJSON(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "building...")
+		fmt.Fprintln(w, "OK")
+	}))
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+
+	appDeployCmd := newAppDeployCmd(tsuruCtx)
+	appDeployCmd.Flags().Parse([]string{"-i", "nginx:latest", "--json"})
+	err := appDeployCmdRun(tsuruCtx, appDeployCmd, []string{"myapp"})
+	assert.NoError(t, err)
+	expected := `{
+  "app": "myapp",
+  "status": "succeeded",
+  "duration": "0s"
+}
+`
+	assert.Equal(t, expected, tsuruCtx.Stdout.(*strings.Builder).String())
+	assert.Equal(t, "Deploying container image...\nbuilding...\nOK\n", tsuruCtx.Stderr.(*strings.Builder).String())
+}
+
+func TestAppDeployRunDockerfile(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, r.ParseMultipartForm(1024*1024))
+		assert.Equal(t, "FROM alpine AS production\n", r.FormValue("dockerfile"))
+		assert.Equal(t, "production", r.FormValue("target"))
+		assert.Equal(t, "1.20", r.FormValue("buildargs.GO_VERSION"))
+		assert.Equal(t, "a=b", r.FormValue("buildargs.OTHER"))
+
+		file, _, err := r.FormFile("file")
+		assert.NoError(t, err)
+		zr, err := gzip.NewReader(file)
+		assert.NoError(t, err)
+		tr := tar.NewReader(zr)
+		names := []string{}
+		for h, err := tr.Next(); err == nil; h, err = tr.Next() {
+			names = append(names, h.Name)
+		}
+		sort.Strings(names)
+		assert.Equal(t, []string{".dockerignore", "Dockerfile.myapp", "main.go"}, names)
+		fmt.Fprintln(w, "OK")
+	}))
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+	afero.WriteFile(tsuruCtx.Fs, "mysite/Dockerfile", []byte("FROM scratch\n"), 0644)
+	afero.WriteFile(tsuruCtx.Fs, "mysite/Dockerfile.myapp", []byte("FROM alpine AS production\n"), 0644)
+	afero.WriteFile(tsuruCtx.Fs, "mysite/main.go", []byte("package main"), 0644)
+	afero.WriteFile(tsuruCtx.Fs, "mysite/secret.env", []byte("not deployed"), 0644)
+	afero.WriteFile(tsuruCtx.Fs, "mysite/.dockerignore", []byte("*.env\nDockerfile\n"), 0644)
+
+	appDeployCmd := newAppDeployCmd(tsuruCtx)
+	appDeployCmd.Flags().Parse([]string{"--dockerfile", "mysite", "--build-target", "production", "--build-arg", "GO_VERSION=1.20", "--build-arg", "OTHER=a=b"})
+	err := appDeployCmdRun(tsuruCtx, appDeployCmd, []string{"myapp"})
+	assert.NoError(t, err)
+}
+
+func TestAppDeployRunDockerfileErrors(t *testing.T) {
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	afero.WriteFile(tsuruCtx.Fs, "mysite/main.go", []byte("package main"), 0644)
+
+	appDeployCmd := newAppDeployCmd(tsuruCtx)
+	appDeployCmd.Flags().Parse([]string{"--dockerfile", "mysite"})
+	err := appDeployCmdRun(tsuruCtx, appDeployCmd, []string{"myapp"})
+	assert.ErrorContains(t, err, "container file not found")
+
+	appDeployCmd = newAppDeployCmd(tsuruCtx)
+	appDeployCmd.Flags().Parse([]string{"--build-arg", "A=1"})
+	err = appDeployCmdRun(tsuruCtx, appDeployCmd, []string{"myapp", "mysite"})
+	assert.EqualError(t, err, "flags --build-arg and --build-target can only be used with --dockerfile")
+
+	afero.WriteFile(tsuruCtx.Fs, "mysite/Dockerfile", []byte("FROM scratch\n"), 0644)
+	appDeployCmd = newAppDeployCmd(tsuruCtx)
+	appDeployCmd.Flags().Parse([]string{"--dockerfile", "mysite/Dockerfile", "--build-arg", "INVALID"})
+	err = appDeployCmdRun(tsuruCtx, appDeployCmd, []string{"myapp"})
+	assert.ErrorContains(t, err, `invalid flag "INVALID"`)
+}
+
+func TestAppDeployRunDryRun(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request on dry run: %s %s", r.Method, r.URL.Path)
+	}))
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+	stdout := strings.Builder{}
+	tsuruCtx.Stdout = &stdout
+	afero.WriteFile(tsuruCtx.Fs, "mysite/index.html", []byte("<html></html>"), 0644)
+	afero.WriteFile(tsuruCtx.Fs, "mysite/debug.log", []byte("some log"), 0644)
+	afero.WriteFile(tsuruCtx.Fs, ".tsuruignore", []byte("*.log\n"), 0644)
+
+	appDeployCmd := newAppDeployCmd(tsuruCtx)
+	appDeployCmd.Flags().Parse([]string{"--dry-run", "-m", "my message"})
+	err := appDeployCmdRun(tsuruCtx, appDeployCmd, []string{"myapp", "mysite"})
+	assert.NoError(t, err)
+	assert.Contains(t, stdout.String(), "Files:            1 added, 1 ignored\n")
+	assert.Contains(t, stdout.String(), "  message  my message\n")
+	assert.Contains(t, stdout.String(), "  mysite/debug.log   debug.log   8B    ignored\n")
+	assert.Contains(t, stdout.String(), "  mysite/index.html  index.html  13B   added\n")
+}
+
+func TestAppDeployRunDryRunJSON(t *testing.T) {
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	stdout := strings.Builder{}
+	tsuruCtx.Stdout = &stdout
+	afero.WriteFile(tsuruCtx.Fs, "mysite/Dockerfile", []byte("FROM scratch\n"), 0644)
+
+	appDeployCmd := newAppDeployCmd(tsuruCtx)
+	appDeployCmd.Flags().Parse([]string{"--dry-run", "--json", "--dockerfile", "mysite"})
+	err := appDeployCmdRun(tsuruCtx, appDeployCmd, []string{"myapp"})
+	assert.NoError(t, err)
+
+	var dryRun deployDryRun
+	assert.NoError(t, json.Unmarshal([]byte(stdout.String()), &dryRun))
+	assert.Equal(t, "myapp", dryRun.App)
+	assert.Equal(t, map[string]string{"origin": "app-deploy", "dockerfile": "FROM scratch\n"}, dryRun.Values)
+	if assert.NotNil(t, dryRun.Archive) {
+		assert.Equal(t, 1, dryRun.Archive.Count("added"))
+		assert.Greater(t, dryRun.Archive.Size, dryRun.Archive.CompressedSize)
+	}
+
+	appDeployCmd = newAppDeployCmd(tsuruCtx)
+	appDeployCmd.Flags().Parse([]string{"--dry-run", "-i", "registry.example.com/app:v1"})
+	err = appDeployCmdRun(tsuruCtx, appDeployCmd, []string{"myapp"})
+	assert.NoError(t, err)
+	assert.Contains(t, stdout.String(), "  image   registry.example.com/app:v1\n")
+}
+
+func TestAppDeployRunManyApps(t *testing.T) {
+	var mu sync.Mutex
+	archives := map[string]string{}
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		appName := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/1.0/apps/"), "/deploy")
+		assert.NoError(t, r.ParseMultipartForm(1024*1024))
+		file, _, err := r.FormFile("file")
+		assert.NoError(t, err)
+		data, _ := io.ReadAll(file)
+		mu.Lock()
+		archives[appName] = string(data)
+		mu.Unlock()
+		if appName == "app2" {
+			fmt.Fprint(w, "building\nexit status 1\n")
+			return
+		}
+		fmt.Fprint(w, "building\nOK\n")
+	}))
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+	afero.WriteFile(tsuruCtx.Fs, "mysite/main.go", []byte("package main"), 0644)
+
+	appDeployCmd := newAppDeployCmd(tsuruCtx)
+	appDeployCmd.Flags().Parse([]string{"-a", "app1", "-a", "app2", "-a", "app3", "--parallel", "2"})
+	err := appDeployCmdRun(tsuruCtx, appDeployCmd, []string{"mysite"})
+	assert.EqualError(t, err, "deploy failed for 1 of 3 apps")
+
+	assert.Len(t, archives, 3)
+	assert.NotEmpty(t, archives["app1"])
+	assert.Equal(t, archives["app1"], archives["app2"])
+	assert.Equal(t, archives["app1"], archives["app3"])
+
+	stdout := tsuruCtx.Stdout.(*strings.Builder).String()
+	for _, line := range []string{"[app1] building\n", "[app1] OK\n", "[app2] exit status 1\n", "[app3] OK\n"} {
+		assert.Contains(t, stdout, line)
+	}
+	assert.True(t, strings.HasSuffix(stdout, `
++------+-----------+----------+---------+-------+
+| App  | Status    | Duration | Version | Error |
++------+-----------+----------+---------+-------+
+| app1 | succeeded | 0s       |         |       |
+| app2 | failed    | 0s       |         |       |
+| app3 | succeeded | 0s       |         |       |
++------+-----------+----------+---------+-------+
+`), stdout)
+}
+
+func TestAppDeployRunTag(t *testing.T) {
+	var mu sync.Mutex
+	deployed := []string{}
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/1.0/apps" {
+			assert.Equal(t, []string{"regional"}, r.URL.Query()["tag"])
+			assert.Equal(t, "true", r.URL.Query().Get("simplified"))
+			fmt.Fprintln(w, `[{"name":"app-us"},{"name":"app-eu"}]`)
+			return
+		}
+		assert.Equal(t, "nginx:latest", r.FormValue("image"))
+		mu.Lock()
+		deployed = append(deployed, r.URL.Path)
+		mu.Unlock()
+		fmt.Fprintln(w, "OK")
+	}))
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+
+	appDeployCmd := newAppDeployCmd(tsuruCtx)
+	appDeployCmd.Flags().Parse([]string{"--tag", "regional", "-i", "nginx:latest", "--json"})
+	err := appDeployCmdRun(tsuruCtx, appDeployCmd, []string{})
+	assert.NoError(t, err)
+	sort.Strings(deployed)
+	assert.Equal(t, []string{"/1.0/apps/app-eu/deploy", "/1.0/apps/app-us/deploy"}, deployed)
+
+	var summaries []deploySummary
+	assert.NoError(t, json.Unmarshal([]byte(tsuruCtx.Stdout.(*strings.Builder).String()), &summaries))
+	if assert.Len(t, summaries, 2) {
+		assert.Equal(t, "app-eu", summaries[0].App)
+		assert.Equal(t, "succeeded", summaries[0].Status)
+	}
+
+	appDeployCmd = newAppDeployCmd(tsuruCtx)
+	appDeployCmd.Flags().Parse([]string{"--tag", "regional", "-a", "myapp", "-i", "nginx:latest"})
+	err = appDeployCmdRun(tsuruCtx, appDeployCmd, []string{})
+	assert.EqualError(t, err, "you can't use --app and --tag at the same time")
+}
+
+func TestAppDeployRunRetriesUpload(t *testing.T) {
+	defer func(backoff time.Duration) { deployRetryBackoff = backoff }(deployRetryBackoff)
+	deployRetryBackoff = 0
+
+	attempts := 0
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/1.1/events" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		attempts++
+		assert.NoError(t, r.ParseMultipartForm(1024*1024))
+		_, _, err := r.FormFile("file")
+		assert.NoError(t, err)
+		if attempts < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "OK")
+	}))
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+	afero.WriteFile(tsuruCtx.Fs, "mysite/main.go", []byte("package main"), 0644)
+
+	appDeployCmd := newAppDeployCmd(tsuruCtx)
+	err := appDeployCmdRun(tsuruCtx, appDeployCmd, []string{"myapp", "mysite"})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, attempts)
+	stderr := tsuruCtx.Stderr.(*strings.Builder).String()
+	assert.Contains(t, stderr, "Upload failed (503 Service Unavailable), retrying in 0s (1 of 3)...\n")
+	assert.Contains(t, stderr, "Upload failed (503 Service Unavailable), retrying in 0s (2 of 3)...\n")
+	assert.Contains(t, stderr, "Uploaded ")
+
+	attempts = 0
+	appDeployCmd = newAppDeployCmd(tsuruCtx)
+	appDeployCmd.Flags().Parse([]string{"--retries", "1", "--force"})
+	err = appDeployCmdRun(tsuruCtx, appDeployCmd, []string{"myapp", "mysite"})
+	assert.ErrorContains(t, err, "deploy failed")
+	assert.Equal(t, 2, attempts)
+}
+
+func TestAppDeployRunSkipsUnchangedArchiveJSON(t *testing.T) {
+	deploys := 0
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			io.Copy(io.Discard, r.Body)
+			deploys++
+			fmt.Fprintln(w, "OK")
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+	afero.WriteFile(tsuruCtx.Fs, "mysite/main.go", []byte("package main"), 0644)
+	deploy := func(args ...string) string {
+		tsuruCtx.Stdout = &strings.Builder{}
+		appDeployCmd := newAppDeployCmd(tsuruCtx)
+		appDeployCmd.Flags().Parse(args)
+		assert.NoError(t, appDeployCmdRun(tsuruCtx, appDeployCmd, appDeployCmd.Flags().Args()))
+		return tsuruCtx.Stdout.(*strings.Builder).String()
+	}
+
+	deploy("-a", "myapp", "-a", "otherapp", "mysite")
+	assert.Equal(t, 2, deploys)
+
+	var summary deploySummary
+	assert.NoError(t, json.Unmarshal([]byte(deploy("-a", "myapp", "--json", "mysite")), &summary))
+	assert.Equal(t, deploySummary{App: "myapp", Status: "skipped"}, summary)
+
+	var summaries []deploySummary
+	assert.NoError(t, json.Unmarshal([]byte(deploy("-a", "myapp", "-a", "otherapp", "--json", "mysite")), &summaries))
+	assert.Equal(t, []deploySummary{{App: "myapp", Status: "skipped"}, {App: "otherapp", Status: "skipped"}}, summaries)
+	assert.Equal(t, 2, deploys)
+}
+
+func TestDeployerUploadDoesntRetryAcceptedDeploys(t *testing.T) {
+	defer func(backoff time.Duration) { deployRetryBackoff = backoff }(deployRetryBackoff)
+	deployRetryBackoff = 0
+
+	attempts := 0
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/1.1/events" {
+			fmt.Fprintln(w, `[{"UniqueID":"ev1","Running":true}]`)
+			return
+		}
+		attempts++
+		w.WriteHeader(http.StatusGatewayTimeout)
+	}))
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+	body, err := newDeployBody(tsuruCtx.Fs, url.Values{"image": {"nginx"}}, nil, nil)
+	assert.NoError(t, err)
+	d := &deployer{tsuruCtx: tsuruCtx, body: body, retries: 3, ctx: context.Background(), events: map[string]string{}}
+
+	_, err = d.upload("myapp", io.Discard, false)
+	assert.EqualError(t, err, `upload failed (504 Gateway Timeout), but a deploy of app "myapp" is running (event ev1), not retrying`)
+	assert.Equal(t, 1, attempts)
+}
+
+// fakeTerminal is a writer passing as a terminal.
+type fakeTerminal struct {
+	strings.Builder
+}
+
+func (*fakeTerminal) IsTerminal() bool { return true }
+
+func TestAppDeployRunProgressBarOnTerminalStderr(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		fmt.Fprintln(w, "OK")
+	}))
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+	stderr := &fakeTerminal{}
+	tsuruCtx.Stderr = stderr
+	afero.WriteFile(tsuruCtx.Fs, "mysite/main.go", []byte("package main"), 0644)
+
+	appDeployCmd := newAppDeployCmd(tsuruCtx)
+	err := appDeployCmdRun(tsuruCtx, appDeployCmd, []string{"myapp", "mysite"})
+	assert.NoError(t, err)
+	assert.Contains(t, stderr.String(), "\r[==============================]")
+
+	tsuruCtx.Stderr = &strings.Builder{}
+	tsuruCtx.Stdin = &tsuructx.FakeStdin{Reader: strings.NewReader(""), Terminal: true}
+	appDeployCmd = newAppDeployCmd(tsuruCtx)
+	appDeployCmd.Flags().Parse([]string{"--force"})
+	err = appDeployCmdRun(tsuruCtx, appDeployCmd, []string{"myapp", "mysite"})
+	assert.NoError(t, err)
+	assert.NotContains(t, tsuruCtx.Stderr.(*strings.Builder).String(), "\r[")
+}
+
+func TestDeployerUploadInterruptedDuringBackoff(t *testing.T) {
+	defer func(backoff time.Duration) { deployRetryBackoff = backoff }(deployRetryBackoff)
+	deployRetryBackoff = time.Hour
+
+	ctx, cancel := context.WithCancel(context.Background())
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		cancel()
+	}))
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+	body, err := newDeployBody(tsuruCtx.Fs, url.Values{"image": {"nginx"}}, nil, nil)
+	assert.NoError(t, err)
+	d := &deployer{tsuruCtx: tsuruCtx, body: body, retries: 3, ctx: ctx, events: map[string]string{}}
+
+	start := time.Now()
+	_, err = d.upload("myapp", io.Discard, false)
+	assert.EqualError(t, err, "deploy interrupted before it started")
+	assert.Less(t, time.Since(start), time.Minute)
+}
+
+func TestAppDeployRunSkipsUnchangedArchive(t *testing.T) {
+	var deployed []string
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			io.Copy(io.Discard, r.Body)
+			deployed = append(deployed, strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/1.0/apps/"), "/deploy"))
+			fmt.Fprintln(w, "OK")
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+	afero.WriteFile(tsuruCtx.Fs, "mysite/main.go", []byte("package main"), 0644)
+	deploy := func(args ...string) {
+		appDeployCmd := newAppDeployCmd(tsuruCtx)
+		appDeployCmd.Flags().Parse(args)
+		assert.NoError(t, appDeployCmdRun(tsuruCtx, appDeployCmd, appDeployCmd.Flags().Args()))
+	}
+
+	deploy("-a", "myapp", "mysite")
+	deploy("-a", "myapp", "-m", "same files", "mysite")
+	assert.Equal(t, []string{"myapp"}, deployed)
+	assert.Contains(t, tsuruCtx.Stderr.(*strings.Builder).String(), `WARNING: nothing changed since the last deploy of app "myapp", skipping it (use --force to deploy anyway).`)
+
+	deploy("-a", "myapp", "-a", "otherapp", "mysite")
+	assert.Equal(t, []string{"myapp", "otherapp"}, deployed)
+
+	deploy("-a", "myapp", "--force", "mysite")
+	afero.WriteFile(tsuruCtx.Fs, "mysite/main.go", []byte("package main // changed"), 0644)
+	deploy("-a", "myapp", "mysite")
+	assert.Equal(t, []string{"myapp", "otherapp", "myapp", "myapp"}, deployed)
+}
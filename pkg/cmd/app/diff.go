@@ -0,0 +1,230 @@
+// Copyright © 2023 tsuru-client authors
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+	"github.com/tsuru/tsuru-client/v2/internal/config"
+	"github.com/tsuru/tsuru-client/v2/internal/parser"
+	"github.com/tsuru/tsuru-client/v2/internal/tsuructx"
+	"github.com/tsuru/tsuru-client/v2/internal/watch"
+	"github.com/tsuru/tsuru-client/v2/pkg/printer"
+	bindTypes "github.com/tsuru/tsuru/types/bind"
+)
+
+func newAppDiffCmd(tsuruCtx *tsuructx.TsuruContext) *cobra.Command {
+	appDiffCmd := &cobra.Command{
+		Use:   "diff APP1[@TARGET] APP2[@TARGET]",
+		Short: "compares the configuration of two apps",
+		Long: `Compares the configuration of two apps: plan, pool, platform, tags, router
+options, autoscale, service instances, volumes and the names of the environment
+variables (their values are not compared).
+
+Each app may be on another target, given by its label or URL after the name of
+the app (eg: myapp@production). Apps without a target are on the current one.
+
+The values only on the first app are shown with "-" and the ones only on the
+second app with "+".`,
+		Example: `$ tsuru app diff myapp-staging myapp
+$ tsuru app diff myapp@staging myapp@production`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return appDiffCmdRun(tsuruCtx, cmd, args)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return completeAppNames(tsuruCtx, cmd, args, toComplete)
+		},
+		Args:        cobra.ExactArgs(2),
+		Annotations: map[string]string{watch.Annotation: "true"},
+	}
+	return appDiffCmd
+}
+
+func appDiffCmdRun(tsuruCtx *tsuructx.TsuruContext, cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+
+	var wg sync.WaitGroup
+	fields := make([][]appDiffField, len(args))
+	errs := make([]error, len(args))
+	for i, arg := range args {
+		wg.Add(1)
+		go func(i int, arg string) {
+			defer wg.Done()
+			fields[i], errs[i] = loadAppDiffFields(tsuruCtx, arg)
+		}(i, arg)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	colorify := printer.Colorify{DisableColors: tsuruCtx.Viper.IsSet("disable-colors")}
+	printAppDiff(tsuruCtx.Stdout, colorify, args[0], args[1], fields[0], fields[1])
+	return nil
+}
+
+// loadAppDiffFields loads the app given as APP[@TARGET] and returns its
+// fields compared by app diff.
+func loadAppDiffFields(tsuruCtx *tsuructx.TsuruContext, appRef string) ([]appDiffField, error) {
+	tsuruCtx, appName, err := appRefContext(tsuruCtx, appRef)
+	if err != nil {
+		return nil, err
+	}
+	a, err := getApp(tsuruCtx, appName)
+	if err != nil {
+		return nil, err
+	}
+	envs, err := getAppEnvs(tsuruCtx, appName)
+	if err != nil {
+		return nil, err
+	}
+	return appDiffFields(a, envs), nil
+}
+
+// appRefContext returns the name of the app given as APP[@TARGET], and the
+// context for its target (the current one when not given).
+func appRefContext(tsuruCtx *tsuructx.TsuruContext, appRef string) (*tsuructx.TsuruContext, string, error) {
+	i := strings.LastIndex(appRef, "@")
+	if i < 0 || i == len(appRef)-1 {
+		return tsuruCtx, strings.TrimSuffix(appRef, "@"), nil
+	}
+	targetCtx, err := tsuruCtxForTarget(tsuruCtx, appRef[i+1:])
+	if err != nil {
+		return nil, "", err
+	}
+	return targetCtx, appRef[:i], nil
+}
+
+// tsuruCtxForTarget returns a copy of tsuruCtx using the target (a label or
+// an URL) and the token saved for it.
+func tsuruCtxForTarget(tsuruCtx *tsuructx.TsuruContext, target string) (*tsuructx.TsuruContext, error) {
+	targetURL, err := config.GetTargetURL(tsuruCtx.Fs, target)
+	if err != nil {
+		return nil, err
+	}
+	token, err := config.GetTargetTokenFromFs(tsuruCtx.Fs, target)
+	if err != nil {
+		return nil, err
+	}
+	return tsuruCtx.WithTarget(targetURL, token), nil
+}
+
+// appDiffField is a field compared by app diff, with its values (only one for
+// single valued fields).
+type appDiffField struct {
+	Name   string
+	Values []string
+}
+
+func appDiffFields(a *app, envs []bindTypes.EnvVar) []appDiffField {
+	cpuMilli, memory := int64(a.Plan.CPUMilli), a.Plan.Memory
+	if a.Plan.Override.CPUMilli != nil {
+		cpuMilli = int64(*a.Plan.Override.CPUMilli)
+	}
+	if a.Plan.Override.Memory != nil {
+		memory = *a.Plan.Override.Memory
+	}
+	plan := fmt.Sprintf("%s (cpu: %s, memory: %s)", a.Plan.Name, limitString(cpuMilli, cpuString), limitString(memory, memoryString))
+
+	var routerOpts, autoScale, services, volumes, envNames []string
+	for key, value := range a.RouterOpts {
+		routerOpts = append(routerOpts, key+"="+value)
+	}
+	for _, as := range a.AutoScale {
+		process := as.Process
+		if as.Version > 0 {
+			process = fmt.Sprintf("%s (v%d)", as.Process, as.Version)
+		}
+		autoScale = append(autoScale, fmt.Sprintf("%s: %d-%d units, cpu %s", process, as.MinUnits, as.MaxUnits, parser.CPUValue(as.AverageCPU)))
+	}
+	for _, sib := range a.ServiceInstanceBinds {
+		services = append(services, sib.Service+"/"+sib.Instance)
+	}
+	for _, vb := range a.VolumeBinds {
+		mode := "rw"
+		if vb.ReadOnly {
+			mode = "ro"
+		}
+		volumes = append(volumes, fmt.Sprintf("%s:%s (%s)", vb.ID.Volume, vb.ID.MountPoint, mode))
+	}
+	for _, env := range envs {
+		envNames = append(envNames, env.Name)
+	}
+
+	fields := []appDiffField{
+		{"Plan", []string{plan}},
+		{"Pool", []string{noneIfEmpty(a.Pool)}},
+		{"Platform", []string{noneIfEmpty(a.Platform)}},
+		{"Tags", append([]string{}, a.Tags...)},
+		{"Router options", routerOpts},
+		{"Autoscale", autoScale},
+		{"Service instances", services},
+		{"Volumes", volumes},
+		{"Environment variables", envNames},
+	}
+	for _, field := range fields {
+		sort.Strings(field.Values)
+	}
+	return fields
+}
+
+func noneIfEmpty(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+	return s
+}
+
+func limitString(value int64, format func(int64) string) string {
+	if value == 0 {
+		return "unlimited"
+	}
+	return format(value)
+}
+
+func printAppDiff(out io.Writer, colorify printer.Colorify, name1, name2 string, fields1, fields2 []appDiffField) {
+	fmt.Fprintf(out, "--- %s\n+++ %s\n", name1, name2)
+	changed := false
+	for i := range fields1 {
+		removed := valuesNotIn(fields1[i].Values, fields2[i].Values)
+		added := valuesNotIn(fields2[i].Values, fields1[i].Values)
+		if len(removed) == 0 && len(added) == 0 {
+			continue
+		}
+		changed = true
+		fmt.Fprintf(out, "%s:\n", fields1[i].Name)
+		for _, v := range removed {
+			fmt.Fprintln(out, colorify.Colorfy("  - "+v, "red", "", ""))
+		}
+		for _, v := range added {
+			fmt.Fprintln(out, colorify.Colorfy("  + "+v, "green", "", ""))
+		}
+	}
+	if !changed {
+		fmt.Fprintln(out, "No differences found.")
+	}
+}
+
+// valuesNotIn returns the values not in others.
+func valuesNotIn(values, others []string) []string {
+	isOther := map[string]bool{}
+	for _, v := range others {
+		isOther[v] = true
+	}
+	var result []string
+	for _, v := range values {
+		if !isOther[v] {
+			result = append(result, v)
+		}
+	}
+	return result
+}
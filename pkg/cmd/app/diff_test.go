@@ -0,0 +1,129 @@
+// Copyright © 2023 tsuru-client authors
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/tsuru/tsuru-client/v2/internal/config"
+	"github.com/tsuru/tsuru-client/v2/internal/tsuructx"
+)
+
+// diffMockServer serves the app and its environment variables, checking the
+// token used.
+func diffMockServer(t *testing.T, token, appName, appJSON, envsJSON string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "bearer "+token, r.Header.Get("Authorization"))
+		switch r.URL.Path {
+		case "/1.0/apps/" + appName:
+			fmt.Fprintln(w, appJSON)
+		case "/1.0/apps/" + appName + "/env":
+			fmt.Fprintln(w, envsJSON)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestAppDiffRun(t *testing.T) {
+	stgServer := diffMockServer(t, "sometoken", "myapp-stg",
+		`{"name":"myapp-stg","platform":"python","pool":"stg","tags":["team:a","env:stg"],
+"plan":{"name":"small","memory":268435456,"cpumilli":250},
+"routeropts":{"domain":"stg.example.com"},
+"autoscale":[{"process":"web","minUnits":1,"maxUnits":2,"averageCPU":"700m"}],
+"serviceInstanceBinds":[{"service":"mysql","instance":"db-stg"}],
+"volumeBinds":[{"ID":{"Volume":"data","MountPoint":"/data"},"ReadOnly":false}]}`,
+		`[{"name":"TSURU_APPNAME","value":"myapp-stg"},{"name":"DEBUG","value":"1"},{"name":"DATABASE_URL","value":"x"}]`)
+	prodServer := diffMockServer(t, "prodtoken", "myapp",
+		`{"name":"myapp","platform":"python","pool":"prod","tags":["team:a","env:prod"],
+"plan":{"name":"small","memory":268435456,"cpumilli":250,"override":{"memory":1073741824}},
+"routeropts":{"domain":"example.com"},
+"autoscale":[{"process":"web","minUnits":2,"maxUnits":10,"averageCPU":"700m"}],
+"serviceInstanceBinds":[{"service":"mysql","instance":"db"}],
+"volumeBinds":[{"ID":{"Volume":"data","MountPoint":"/data"},"ReadOnly":false}]}`,
+		`[{"name":"TSURU_APPNAME","value":"myapp"},{"name":"DATABASE_URL","value":"y"},{"name":"SENTRY_DSN","value":"z"}]`)
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(stgServer.URL)
+	tsuruCtx.Viper.Set("disable-colors", true)
+	afero.WriteFile(tsuruCtx.Fs, filepath.Join(config.ConfigPath, "targets"), []byte("prod "+prodServer.URL+"\n"), 0600)
+	afero.WriteFile(tsuruCtx.Fs, filepath.Join(config.ConfigPath, "token.d", "prod"), []byte("prodtoken\n"), 0600)
+
+	cmd := newAppDiffCmd(tsuruCtx)
+	err := appDiffCmdRun(tsuruCtx, cmd, []string{"myapp-stg", "myapp@prod"})
+	assert.NoError(t, err)
+	expected := `--- myapp-stg
++++ myapp@prod
+Plan:
+  - small (cpu: 250m, memory: 256Mi)
+  + small (cpu: 250m, memory: 1Gi)
+Pool:
+  - stg
+  + prod
+Tags:
+  - env:stg
+  + env:prod
+Router options:
+  - domain=stg.example.com
+  + domain=example.com
+Autoscale:
+  - web: 1-2 units, cpu 70%
+  + web: 2-10 units, cpu 70%
+Service instances:
+  - mysql/db-stg
+  + mysql/db
+Environment variables:
+  - DEBUG
+  + SENTRY_DSN
+`
+	assert.Equal(t, expected, tsuruCtx.Stdout.(*strings.Builder).String())
+	assert.Equal(t, stgServer.URL, tsuruCtx.TargetURL())
+}
+
+func TestAppDiffRunNoDifferences(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/env") {
+			fmt.Fprintln(w, `[{"name":"DEBUG","value":"1"}]`)
+			return
+		}
+		fmt.Fprintln(w, `{"name":"myapp","pool":"mypool","tags":["b","a"]}`)
+	}))
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+	afero.WriteFile(tsuruCtx.Fs, filepath.Join(config.ConfigPath, "targets"), []byte("other "+mockServer.URL+"\n"), 0600)
+	afero.WriteFile(tsuruCtx.Fs, filepath.Join(config.ConfigPath, "token.d", "other"), []byte("sometoken\n"), 0600)
+
+	cmd := newAppDiffCmd(tsuruCtx)
+	err := appDiffCmdRun(tsuruCtx, cmd, []string{"myapp", "myapp@" + mockServer.URL})
+	assert.NoError(t, err)
+	assert.Equal(t, "--- myapp\n+++ myapp@"+mockServer.URL+"\nNo differences found.\n", tsuruCtx.Stdout.(*strings.Builder).String())
+}
+
+func TestAppDiffRunAppNotFound(t *testing.T) {
+	mockServer := httptest.NewServer(http.NotFoundHandler())
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+
+	cmd := newAppDiffCmd(tsuruCtx)
+	err := appDiffCmdRun(tsuruCtx, cmd, []string{"myapp", "otherapp"})
+	assert.EqualError(t, err, `app "myapp" not found`)
+}
+
+func TestAppDiffRunNotLoggedInToTarget(t *testing.T) {
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL("http://stg.example.com")
+	afero.WriteFile(tsuruCtx.Fs, filepath.Join(config.ConfigPath, "targets"), []byte("prod https://tsuru.example.com\n"), 0600)
+	afero.WriteFile(tsuruCtx.Fs, filepath.Join(config.ConfigPath, "token"), []byte("stgtoken\n"), 0600)
+
+	cmd := newAppDiffCmd(tsuruCtx)
+	err := appDiffCmdRun(tsuruCtx, cmd, []string{"myapp@prod", "myapp"})
+	assert.EqualError(t, err, `not logged in to target "prod"`)
+}
@@ -0,0 +1,385 @@
+// Copyright © 2023 tsuru-client authors
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/tsuru/tsuru-client/v2/internal/tsuructx"
+	"github.com/tsuru/tsuru-client/v2/internal/watch"
+	"github.com/tsuru/tsuru-client/v2/pkg/printer"
+	apiTypes "github.com/tsuru/tsuru/types/api"
+	bindTypes "github.com/tsuru/tsuru/types/bind"
+)
+
+// privateEnvMask replaces the values of private environment variables, unless
+// --show-private is given.
+const privateEnvMask = "*** (private variable)"
+
+// tsuruEnvs are the environment variables set by tsuru itself. They can't be
+// changed, so they are left out of exports, imports and syncs.
+var tsuruEnvs = []string{"TSURU_APPNAME", "TSURU_APPDIR", "TSURU_APP_TOKEN", "TSURU_SERVICE", "TSURU_SERVICES"}
+
+func newAppEnvCmd(tsuruCtx *tsuructx.TsuruContext) *cobra.Command {
+	appEnvCmd := &cobra.Command{
+		Use:   "env",
+		Short: "manages the environment variables of apps",
+		Long: `Manages the environment variables of apps: shows, sets and unsets them, and
+exports or imports them (eg: from a .env file).
+
+The values of private variables are masked, unless --show-private is given.`,
+		Args: cobra.NoArgs,
+	}
+
+	appEnvCmd.AddCommand(newAppEnvGetCmd(tsuruCtx))
+	appEnvCmd.AddCommand(newAppEnvSetCmd(tsuruCtx))
+	appEnvCmd.AddCommand(newAppEnvUnsetCmd(tsuruCtx))
+	appEnvCmd.AddCommand(newAppEnvExportCmd(tsuruCtx))
+	appEnvCmd.AddCommand(newAppEnvImportCmd(tsuruCtx))
+	appEnvCmd.AddCommand(newAppEnvSyncCmd(tsuruCtx))
+	return appEnvCmd
+}
+
+func newAppEnvGetCmd(tsuruCtx *tsuructx.TsuruContext) *cobra.Command {
+	appEnvGetCmd := &cobra.Command{
+		Use:   "get [APP] [NAME...]",
+		Short: "shows the environment variables of an app",
+		Long: `Shows the environment variables of an app, or only the given ones.
+The values of private variables are masked, unless [[--show-private]] is given.`,
+		Example: `$ tsuru app env get myapp
+$ tsuru app env get -a myapp DATABASE_URL REDIS_URL --show-private`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return appEnvGetCmdRun(tsuruCtx, cmd, args)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return completeAppNames(tsuruCtx, cmd, args, toComplete)
+		},
+		Args:        cobra.ArbitraryArgs,
+		Annotations: map[string]string{watch.Annotation: "true"},
+	}
+
+	appEnvGetCmd.Flags().StringP("app", "a", "", "The name of the app (may be passed as argument)")
+	appEnvGetCmd.Flags().Bool("show-private", false, "Show the values of private variables")
+	appEnvGetCmd.Flags().StringP("output", "o", "table", "Output format: table, json or yaml")
+	return appEnvGetCmd
+}
+
+func appEnvGetCmdRun(tsuruCtx *tsuructx.TsuruContext, cmd *cobra.Command, args []string) error {
+	appName, names, err := appNameAndArgs(cmd, args)
+	if err != nil {
+		return err
+	}
+	cmd.SilenceUsage = true
+
+	envs, err := getAppEnvs(tsuruCtx, appName, names...)
+	if err != nil {
+		return err
+	}
+	showPrivate, _ := cmd.Flags().GetBool("show-private")
+	views := envVarViews(envs, showPrivate)
+	switch printer.FormatAs(cmd.Flag("output").Value.String()) {
+	case printer.JSON:
+		return printer.PrintPrettyJSON(tsuruCtx.Stdout, views)
+	case printer.YAML:
+		return printer.PrintYAML(tsuruCtx.Stdout, views)
+	}
+	for _, v := range views {
+		fmt.Fprintf(tsuruCtx.Stdout, "%s=%s\n", v.Name, v.Value)
+	}
+	return nil
+}
+
+func newAppEnvSetCmd(tsuruCtx *tsuructx.TsuruContext) *cobra.Command {
+	appEnvSetCmd := &cobra.Command{
+		Use:   "set [APP] NAME=value [NAME=value...]",
+		Short: "sets environment variables of an app",
+		Long: `Sets environment variables of an app. The app is restarted to use them, unless
+[[--no-restart]] is given.
+
+The variables are the same for all the processes of the app: with [[--process]]
+only the units of that process are restarted, the other processes get the new
+values when they are restarted.`,
+		Example: `$ tsuru app env set myapp DEBUG=1 WORKERS=4
+$ tsuru app env set -a myapp DATABASE_PASSWORD='s3cr3t' --private
+$ tsuru app env set myapp QUEUE_SIZE=100 --process worker`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return appEnvSetCmdRun(tsuruCtx, cmd, args)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return completeAppNames(tsuruCtx, cmd, args, toComplete)
+		},
+		Args: cobra.MinimumNArgs(1),
+	}
+
+	appEnvSetCmd.Flags().StringP("app", "a", "", "The name of the app (may be passed as argument)")
+	appEnvSetCmd.Flags().BoolP("private", "p", false, "Set the variables as private, masking their values")
+	addEnvRestartFlags(appEnvSetCmd)
+	return appEnvSetCmd
+}
+
+func appEnvSetCmdRun(tsuruCtx *tsuructx.TsuruContext, cmd *cobra.Command, args []string) error {
+	appName, args, err := appNameAndArgs(cmd, args)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("no environment variable was provided, they must be in the form NAME=value")
+	}
+	envs := make([]apiTypes.Env, len(args))
+	for i, arg := range args {
+		name, value, ok := strings.Cut(arg, "=")
+		if !ok || name == "" {
+			return fmt.Errorf("invalid environment variable %q, it must be in the form NAME=value", arg)
+		}
+		envs[i] = apiTypes.Env{Name: name, Value: value}
+	}
+	process, noRestart, err := envRestartFlags(cmd)
+	if err != nil {
+		return err
+	}
+	cmd.SilenceUsage = true
+
+	private, _ := cmd.Flags().GetBool("private")
+	return setAppEnvs(tsuruCtx, appName, apiTypes.Envs{Envs: envs, Private: private, NoRestart: noRestart}, process)
+}
+
+func newAppEnvUnsetCmd(tsuruCtx *tsuructx.TsuruContext) *cobra.Command {
+	appEnvUnsetCmd := &cobra.Command{
+		Use:   "unset [APP] NAME [NAME...]",
+		Short: "unsets environment variables of an app",
+		Long: `Unsets environment variables of an app. The app is restarted, unless
+[[--no-restart]] is given, or only the units of a process with [[--process]].`,
+		Example: `$ tsuru app env unset myapp DEBUG
+$ tsuru app env unset -a myapp DEBUG WORKERS --no-restart`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return appEnvUnsetCmdRun(tsuruCtx, cmd, args)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return completeAppNames(tsuruCtx, cmd, args, toComplete)
+		},
+		Args: cobra.MinimumNArgs(1),
+	}
+
+	appEnvUnsetCmd.Flags().StringP("app", "a", "", "The name of the app (may be passed as argument)")
+	addEnvRestartFlags(appEnvUnsetCmd)
+	return appEnvUnsetCmd
+}
+
+func appEnvUnsetCmdRun(tsuruCtx *tsuructx.TsuruContext, cmd *cobra.Command, args []string) error {
+	appName, names, err := appNameAndArgs(cmd, args)
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("no environment variable was provided")
+	}
+	process, noRestart, err := envRestartFlags(cmd)
+	if err != nil {
+		return err
+	}
+	cmd.SilenceUsage = true
+
+	return unsetAppEnvs(tsuruCtx, appName, names, noRestart, process)
+}
+
+// appNameAndArgs returns the name of the app, given by --app or as the first
+// argument, and the other arguments.
+func appNameAndArgs(cmd *cobra.Command, args []string) (string, []string, error) {
+	appName := cmd.Flag("app").Value.String()
+	if appName == "" && len(args) > 0 {
+		appName, args = args[0], args[1:]
+	}
+	if appName == "" {
+		return "", nil, fmt.Errorf("no app was provided. Please provide an app name or use the --app flag")
+	}
+	return appName, args, nil
+}
+
+func addEnvRestartFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("no-restart", false, "Don't restart the app after changing the variables")
+	cmd.Flags().String("process", "", "Restart only the units of the given process")
+}
+
+// envRestartFlags returns the process to restart after changing environment
+// variables (empty for the whole app), and whether tsuru must not restart the
+// app, which it doesn't when only a process is restarted.
+func envRestartFlags(cmd *cobra.Command) (process string, noRestart bool, err error) {
+	process = cmd.Flag("process").Value.String()
+	noRestart, _ = cmd.Flags().GetBool("no-restart")
+	if process != "" && noRestart {
+		return "", false, fmt.Errorf("either use --process or --no-restart, not both")
+	}
+	return process, noRestart || process != "", nil
+}
+
+// getAppEnvs returns the environment variables of the app, only the ones
+// with the given names when names are given.
+func getAppEnvs(tsuruCtx *tsuructx.TsuruContext, appName string, names ...string) ([]bindTypes.EnvVar, error) {
+	request, err := tsuruCtx.NewRequest("GET", "/apps/"+appName+"/env", nil)
+	if err != nil {
+		return nil, err
+	}
+	request.URL.RawQuery = url.Values{"env": names}.Encode()
+	httpResponse, err := tsuruCtx.RawHTTPClient().Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResponse.Body.Close()
+	if httpResponse.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("app %q not found", appName)
+	}
+	if httpResponse.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(httpResponse.Body)
+		return nil, fmt.Errorf("failed to get the environment variables of app %q: %s", appName, strings.TrimSpace(string(respBody)))
+	}
+	var envs []bindTypes.EnvVar
+	if err = json.NewDecoder(httpResponse.Body).Decode(&envs); err != nil {
+		return nil, err
+	}
+	return envs, nil
+}
+
+// setAppEnvs sets the environment variables of the app, restarting only the
+// units of process when given.
+func setAppEnvs(tsuruCtx *tsuructx.TsuruContext, appName string, envs apiTypes.Envs, process string) error {
+	body, err := json.Marshal(envs)
+	if err != nil {
+		return err
+	}
+	request, err := tsuruCtx.NewRequest("POST", "/apps/"+appName+"/env", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if err = doEnvRequest(tsuruCtx, request, "set", appName); err != nil {
+		return err
+	}
+	if process != "" {
+		return appLifecycleAction(tsuruCtx, "restart", appName, unitFilter{process: process})
+	}
+	return nil
+}
+
+// unsetAppEnvs unsets the environment variables of the app, restarting only
+// the units of process when given.
+func unsetAppEnvs(tsuruCtx *tsuructx.TsuruContext, appName string, names []string, noRestart bool, process string) error {
+	request, err := tsuruCtx.NewRequest("DELETE", "/apps/"+appName+"/env", nil)
+	if err != nil {
+		return err
+	}
+	qs := url.Values{"env": names}
+	if noRestart {
+		qs.Set("noRestart", "true")
+	}
+	request.URL.RawQuery = qs.Encode()
+	if err = doEnvRequest(tsuruCtx, request, "unset", appName); err != nil {
+		return err
+	}
+	if process != "" {
+		return appLifecycleAction(tsuruCtx, "restart", appName, unitFilter{process: process})
+	}
+	return nil
+}
+
+func doEnvRequest(tsuruCtx *tsuructx.TsuruContext, request *http.Request, action, appName string) error {
+	httpResponse, err := tsuruCtx.RawHTTPClient().Do(request)
+	if err != nil {
+		return err
+	}
+	defer httpResponse.Body.Close()
+	if httpResponse.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("app %q not found", appName)
+	}
+	if httpResponse.StatusCode >= http.StatusBadRequest {
+		respBody, _ := io.ReadAll(httpResponse.Body)
+		return fmt.Errorf("failed to %s the environment variables of app %q: %s", action, appName, strings.TrimSpace(string(respBody)))
+	}
+	if _, err = streamOutput(tsuruCtx.Stdout, httpResponse.Body); err != nil {
+		return fmt.Errorf("failed to %s the environment variables of app %q: %w", action, appName, err)
+	}
+	return nil
+}
+
+const (
+	envAdded   = "+"
+	envChanged = "~"
+	envRemoved = "-"
+)
+
+// envChange is a change to an environment variable of an app.
+type envChange struct {
+	Name    string
+	Before  string
+	After   string
+	Kind    string // envAdded, envChanged or envRemoved
+	Private bool
+}
+
+// printEnvChanges writes the changes, masking the values of the private
+// variables.
+func printEnvChanges(out io.Writer, colorify printer.Colorify, changes []envChange) {
+	for _, c := range changes {
+		var line string
+		switch {
+		case c.Kind == envRemoved:
+			line = colorify.Colorfy("  - "+c.Name, "red", "", "")
+		case c.Kind == envAdded && c.Private:
+			line = colorify.Colorfy(fmt.Sprintf("  + %s=%s", c.Name, privateEnvMask), "green", "", "")
+		case c.Kind == envAdded:
+			line = colorify.Colorfy(fmt.Sprintf("  + %s=%s", c.Name, c.After), "green", "", "")
+		case c.Before == c.After && c.Private:
+			line = colorify.Colorfy(fmt.Sprintf("  ~ %s (now private)", c.Name), "yellow", "", "")
+		case c.Before == c.After:
+			line = colorify.Colorfy(fmt.Sprintf("  ~ %s (now public)", c.Name), "yellow", "", "")
+		case c.Private:
+			line = colorify.Colorfy(fmt.Sprintf("  ~ %s (private, value changed)", c.Name), "yellow", "", "")
+		default:
+			line = colorify.Colorfy(fmt.Sprintf("  ~ %s: %s -> %s", c.Name, c.Before, c.After), "yellow", "", "")
+		}
+		fmt.Fprintln(out, line)
+	}
+}
+
+// envChangesToSet returns the variables added or changed by changes.
+func envChangesToSet(changes []envChange) []apiTypes.Env {
+	var envs []apiTypes.Env
+	for _, c := range changes {
+		if c.Kind == envRemoved {
+			continue
+		}
+		private := c.Private
+		envs = append(envs, apiTypes.Env{Name: c.Name, Value: c.After, Private: &private})
+	}
+	return envs
+}
+
+// envVarView is an environment variable as shown by the env commands.
+type envVarView struct {
+	Name    string `json:"name"`
+	Value   string `json:"value"`
+	Private bool   `json:"private"`
+}
+
+// envVarViews returns the variables sorted by name, with the values of the
+// private ones masked unless showPrivate.
+func envVarViews(envs []bindTypes.EnvVar, showPrivate bool) []envVarView {
+	views := make([]envVarView, len(envs))
+	for i, env := range envs {
+		views[i] = envVarView{Name: env.Name, Value: env.Value, Private: !env.Public}
+		if !env.Public && !showPrivate {
+			views[i].Value = privateEnvMask
+		}
+	}
+	sort.Slice(views, func(i, j int) bool { return views[i].Name < views[j].Name })
+	return views
+}
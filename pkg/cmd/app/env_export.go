@@ -0,0 +1,104 @@
+// Copyright © 2023 tsuru-client authors
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/tsuru/tsuru-client/v2/internal/tsuructx"
+	"github.com/tsuru/tsuru-client/v2/internal/watch"
+	"github.com/tsuru/tsuru-client/v2/pkg/printer"
+)
+
+func newAppEnvExportCmd(tsuruCtx *tsuructx.TsuruContext) *cobra.Command {
+	appEnvExportCmd := &cobra.Command{
+		Use:   "export [APP]",
+		Short: "exports the environment variables of an app",
+		Long: `Exports the environment variables of an app as a .env file (dotenv), json or
+yaml. The dotenv format may be imported with "tsuru app env import", the json
+and yaml ones are meant for scripts.
+
+Private variables are left out, unless [[--show-private]] is given. The
+dotenv format does not say which variables are private, so they are imported
+as public ones unless they are already private on the app or [[--private]] is
+given to the import. The variables set by tsuru (eg: TSURU_APPNAME) are always
+left out.`,
+		Example: `$ tsuru app env export myapp > .env
+$ tsuru app env export -a myapp --format json --show-private`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return appEnvExportCmdRun(tsuruCtx, cmd, args)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return completeAppNames(tsuruCtx, cmd, args, toComplete)
+		},
+		Args:        cobra.RangeArgs(0, 1),
+		Annotations: map[string]string{watch.Annotation: "true"},
+	}
+
+	appEnvExportCmd.Flags().StringP("app", "a", "", "The name of the app (may be passed as argument)")
+	appEnvExportCmd.Flags().String("format", "dotenv", "Format of the variables: dotenv, json or yaml")
+	appEnvExportCmd.Flags().Bool("show-private", false, "Export the private variables too, with their values")
+	return appEnvExportCmd
+}
+
+func appEnvExportCmdRun(tsuruCtx *tsuructx.TsuruContext, cmd *cobra.Command, args []string) error {
+	appName, err := appNameFromArgsOrFlag(cmd, args)
+	if err != nil {
+		return err
+	}
+	format := cmd.Flag("format").Value.String()
+	if format != "dotenv" && format != "json" && format != "yaml" {
+		return fmt.Errorf("invalid format %q, it must be one of: dotenv, json or yaml", format)
+	}
+	cmd.SilenceUsage = true
+
+	envs, err := getAppEnvs(tsuruCtx, appName)
+	if err != nil {
+		return err
+	}
+	showPrivate, _ := cmd.Flags().GetBool("show-private")
+	var views []envVarView
+	var privateNames []string
+	for _, v := range envVarViews(envs, showPrivate) {
+		switch {
+		case printer.Contains(tsuruEnvs, v.Name):
+		case v.Private && !showPrivate:
+			privateNames = append(privateNames, v.Name)
+		default:
+			views = append(views, v)
+		}
+	}
+	if len(privateNames) > 0 {
+		fmt.Fprintf(tsuruCtx.Stderr, "The private variables were left out (%s), use --show-private to export them.\n", strings.Join(privateNames, ", "))
+	}
+
+	switch format {
+	case "json":
+		return printer.PrintPrettyJSON(tsuruCtx.Stdout, views)
+	case "yaml":
+		return printer.PrintYAML(tsuruCtx.Stdout, views)
+	}
+	writeDotenv(tsuruCtx.Stdout, views)
+	return nil
+}
+
+var dotenvPlainValue = regexp.MustCompile(`^[\w./:@%+,=-]*$`)
+
+// writeDotenv writes the variables as NAME=value lines, quoting the values
+// which have spaces, quotes or other special characters.
+func writeDotenv(out io.Writer, views []envVarView) {
+	quoter := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`, "$", `\$`)
+	for _, v := range views {
+		value := v.Value
+		if !dotenvPlainValue.MatchString(value) {
+			value = `"` + quoter.Replace(value) + `"`
+		}
+		fmt.Fprintf(out, "%s=%s\n", v.Name, value)
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright © 2023 tsuru-client authors
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tsuru/tsuru-client/v2/internal/tsuructx"
+)
+
+func TestAppEnvExportDotenv(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `[{"name":"URL","value":"https://example.com/a?b=c","public":true},
+{"name":"GREETING","value":"hello \"world\"\n$HOME","public":true},
+{"name":"TOKEN","value":"abc","public":false},
+{"name":"TSURU_APPNAME","value":"myapp","public":true}]`)
+	}))
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+
+	cmd := newAppEnvExportCmd(tsuruCtx)
+	err := appEnvExportCmdRun(tsuruCtx, cmd, []string{"myapp"})
+	assert.NoError(t, err)
+	stdout := tsuruCtx.Stdout.(*strings.Builder).String()
+	expected := `GREETING="hello \"world\"\n\$HOME"
+URL="https://example.com/a?b=c"
+`
+	assert.Equal(t, expected, stdout)
+	assert.Equal(t, "The private variables were left out (TOKEN), use --show-private to export them.\n", tsuruCtx.Stderr.(*strings.Builder).String())
+
+	views, err := parseDotenv([]byte(stdout))
+	assert.NoError(t, err)
+	assert.Equal(t, []envVarView{
+		{Name: "GREETING", Value: "hello \"world\"\n$HOME"},
+		{Name: "URL", Value: "https://example.com/a?b=c"},
+	}, views)
+}
+
+func TestAppEnvExportShowPrivateYAML(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `[{"name":"DEBUG","value":"1","public":true},{"name":"TOKEN","value":"abc","public":false}]`)
+	}))
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+
+	cmd := newAppEnvExportCmd(tsuruCtx)
+	cmd.Flags().Parse([]string{"-a", "myapp", "--format", "yaml", "--show-private"})
+	err := appEnvExportCmdRun(tsuruCtx, cmd, nil)
+	assert.NoError(t, err)
+	expected := `- name: DEBUG
+  value: "1"
+  private: false
+- name: TOKEN
+  value: abc
+  private: true
+`
+	assert.Equal(t, expected, tsuruCtx.Stdout.(*strings.Builder).String())
+	assert.Equal(t, "", tsuruCtx.Stderr.(*strings.Builder).String())
+}
+
+func TestAppEnvExportInvalidFormat(t *testing.T) {
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	cmd := newAppEnvExportCmd(tsuruCtx)
+	cmd.Flags().Parse([]string{"--format", "toml"})
+	err := appEnvExportCmdRun(tsuruCtx, cmd, []string{"myapp"})
+	assert.EqualError(t, err, `invalid format "toml", it must be one of: dotenv, json or yaml`)
+}
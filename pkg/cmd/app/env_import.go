@@ -0,0 +1,226 @@
+// Copyright © 2023 tsuru-client authors
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/tsuru/tsuru-client/v2/internal/tsuructx"
+	"github.com/tsuru/tsuru-client/v2/pkg/printer"
+	apiTypes "github.com/tsuru/tsuru/types/api"
+	bindTypes "github.com/tsuru/tsuru/types/bind"
+)
+
+func newAppEnvImportCmd(tsuruCtx *tsuructx.TsuruContext) *cobra.Command {
+	appEnvImportCmd := &cobra.Command{
+		Use:   "import [APP] -f FILE",
+		Short: "imports environment variables to an app from a .env file",
+		Long: `Imports environment variables to an app from a .env file (dotenv), with one
+NAME=value per line. Values may be quoted, and lines starting with # are
+comments.
+
+The variables added or changed on the app are shown before setting them,
+asking for confirmation unless [[--yes]] is given. The variables of the app
+missing on the file are kept. Variables already private on the app stay
+private.`,
+		Example: `$ tsuru app env import myapp -f .env
+$ tsuru app env export myapp-staging | tsuru app env import myapp -f - --yes`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return appEnvImportCmdRun(tsuruCtx, cmd, args)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return completeAppNames(tsuruCtx, cmd, args, toComplete)
+		},
+		Args: cobra.RangeArgs(0, 1),
+	}
+
+	appEnvImportCmd.Flags().StringP("app", "a", "", "The name of the app (may be passed as argument)")
+	appEnvImportCmd.Flags().StringP("file", "f", "", "The .env file to import (- reads from stdin)")
+	appEnvImportCmd.Flags().BoolP("private", "p", false, "Set the imported variables as private")
+	appEnvImportCmd.Flags().BoolP("yes", "y", false, "Don't ask for confirmation")
+	addEnvRestartFlags(appEnvImportCmd)
+	return appEnvImportCmd
+}
+
+func appEnvImportCmdRun(tsuruCtx *tsuructx.TsuruContext, cmd *cobra.Command, args []string) error {
+	appName, err := appNameFromArgsOrFlag(cmd, args)
+	if err != nil {
+		return err
+	}
+	file := cmd.Flag("file").Value.String()
+	if file == "" {
+		return fmt.Errorf("no file was provided. Please use the --file flag")
+	}
+	yes, _ := cmd.Flags().GetBool("yes")
+	if file == "-" && !yes {
+		return fmt.Errorf("--yes is required to read the variables from stdin")
+	}
+	process, noRestart, err := envRestartFlags(cmd)
+	if err != nil {
+		return err
+	}
+	cmd.SilenceUsage = true
+
+	var data []byte
+	if file == "-" {
+		data, err = io.ReadAll(tsuruCtx.Stdin)
+	} else {
+		data, err = afero.ReadFile(tsuruCtx.Fs, file)
+	}
+	if err != nil {
+		return err
+	}
+	imported, err := parseDotenv(data)
+	if err != nil {
+		return fmt.Errorf("invalid file %q: %w", file, err)
+	}
+	for _, v := range imported {
+		if printer.Contains(tsuruEnvs, v.Name) {
+			return fmt.Errorf("invalid file %q: %s is set by tsuru and can't be imported", file, v.Name)
+		}
+		if v.Value == privateEnvMask {
+			return fmt.Errorf("invalid file %q: the value of %s is masked, export it with --show-private", file, v.Name)
+		}
+	}
+
+	current, err := getAppEnvs(tsuruCtx, appName)
+	if err != nil {
+		return err
+	}
+	private, _ := cmd.Flags().GetBool("private")
+	changes := importEnvChanges(current, imported, private)
+	if len(changes) == 0 {
+		fmt.Fprintf(tsuruCtx.Stdout, "No changes to the environment variables of app %q.\n", appName)
+		return nil
+	}
+	colorify := printer.Colorify{DisableColors: tsuruCtx.Viper.IsSet("disable-colors")}
+	fmt.Fprintf(tsuruCtx.Stdout, "Changes to the environment variables of app %q:\n", appName)
+	printEnvChanges(tsuruCtx.Stdout, colorify, changes)
+	if !yes && !confirm(tsuruCtx, fmt.Sprintf("Apply these changes to app %q?", appName)) {
+		return nil
+	}
+	return setAppEnvs(tsuruCtx, appName, apiTypes.Envs{Envs: envChangesToSet(changes), NoRestart: noRestart}, process)
+}
+
+// importEnvChanges returns the variables imported which are new on the app or
+// have other values (or become private).
+func importEnvChanges(current []bindTypes.EnvVar, imported []envVarView, private bool) []envChange {
+	byName := map[string]bindTypes.EnvVar{}
+	for _, env := range current {
+		byName[env.Name] = env
+	}
+	var changes []envChange
+	for _, v := range imported {
+		env, exists := byName[v.Name]
+		switch {
+		case !exists:
+			changes = append(changes, envChange{Name: v.Name, After: v.Value, Kind: envAdded, Private: private})
+		case env.Value != v.Value || (private && env.Public):
+			changes = append(changes, envChange{Name: v.Name, Before: env.Value, After: v.Value, Kind: envChanged, Private: private || !env.Public})
+		}
+	}
+	return changes
+}
+
+var envNameRegexp = regexp.MustCompile(`^[_a-zA-Z][_a-zA-Z0-9]*$`)
+
+// parseDotenv parses the NAME=value lines of a .env file, sorted by name. The
+// values may be quoted: with double quotes, \n, \t, \", \\ and \$ are escapes;
+// with single quotes, the value is used as it is.
+func parseDotenv(data []byte) ([]envVarView, error) {
+	byName := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		name, value, ok := strings.Cut(line, "=")
+		name = strings.TrimSpace(name)
+		if !ok {
+			return nil, fmt.Errorf("line %d: %q must be in the form NAME=value", n, line)
+		}
+		if !envNameRegexp.MatchString(name) {
+			return nil, fmt.Errorf("line %d: invalid variable name %q", n, name)
+		}
+		value, err := parseDotenvValue(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		byName[name] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	views := make([]envVarView, 0, len(byName))
+	for name, value := range byName {
+		views = append(views, envVarView{Name: name, Value: value})
+	}
+	sort.Slice(views, func(i, j int) bool { return views[i].Name < views[j].Name })
+	return views, nil
+}
+
+func parseDotenvValue(s string) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+	var value strings.Builder
+	var rest string
+	switch s[0] {
+	case '\'':
+		end := strings.IndexByte(s[1:], '\'')
+		if end < 0 {
+			return "", fmt.Errorf("unterminated quoted value %s", s)
+		}
+		value.WriteString(s[1 : end+1])
+		rest = s[end+2:]
+	case '"':
+		i := 1
+		for ; i < len(s) && s[i] != '"'; i++ {
+			if s[i] != '\\' || i+1 == len(s) {
+				value.WriteByte(s[i])
+				continue
+			}
+			i++
+			switch s[i] {
+			case 'n':
+				value.WriteByte('\n')
+			case 'r':
+				value.WriteByte('\r')
+			case 't':
+				value.WriteByte('\t')
+			case '"', '\\', '$':
+				value.WriteByte(s[i])
+			default:
+				value.WriteByte('\\')
+				value.WriteByte(s[i])
+			}
+		}
+		if i == len(s) {
+			return "", fmt.Errorf("unterminated quoted value %s", s)
+		}
+		rest = s[i+1:]
+	default:
+		// unquoted values end on comments
+		if i := strings.Index(s, " #"); i >= 0 {
+			s = s[:i]
+		}
+		return strings.TrimSpace(s), nil
+	}
+	if rest = strings.TrimSpace(rest); rest != "" && !strings.HasPrefix(rest, "#") {
+		return "", fmt.Errorf("unexpected %q after quoted value", rest)
+	}
+	return value.String(), nil
+}
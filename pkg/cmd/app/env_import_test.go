@@ -0,0 +1,151 @@
+// Copyright © 2023 tsuru-client authors
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/tsuru/tsuru-client/v2/internal/tsuructx"
+	apiTypes "github.com/tsuru/tsuru/types/api"
+)
+
+func TestParseDotenv(t *testing.T) {
+	data := `# comment
+export A=1
+B = two words # comment
+C="quoted \"value\"\twith\\escapes\n" # comment
+D='single $quoted \n'
+E=
+A=overridden
+`
+	views, err := parseDotenv([]byte(data))
+	assert.NoError(t, err)
+	assert.Equal(t, []envVarView{
+		{Name: "A", Value: "overridden"},
+		{Name: "B", Value: "two words"},
+		{Name: "C", Value: "quoted \"value\"\twith\\escapes\n"},
+		{Name: "D", Value: `single $quoted \n`},
+		{Name: "E", Value: ""},
+	}, views)
+}
+
+func TestParseDotenvErrors(t *testing.T) {
+	for _, test := range []struct {
+		data     string
+		expected string
+	}{
+		{"A=1\nB", `line 2: "B" must be in the form NAME=value`},
+		{"1A=1", `line 1: invalid variable name "1A"`},
+		{`A="unterminated`, `line 1: unterminated quoted value "unterminated`},
+		{`A='x' y`, `line 1: unexpected "y" after quoted value`},
+	} {
+		_, err := parseDotenv([]byte(test.data))
+		assert.EqualError(t, err, test.expected)
+	}
+}
+
+func envImportMockServer(t *testing.T, envs *apiTypes.Envs) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/1.0/apps/myapp/env", r.URL.Path)
+		if r.Method == "GET" {
+			fmt.Fprintln(w, `[{"name":"DEBUG","value":"0","public":true},{"name":"TOKEN","value":"abc","public":false},{"name":"KEEP","value":"1","public":true}]`)
+			return
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(envs))
+		fmt.Fprintln(w, `{"Message":"---- Setting 3 new environment variables ----\n"}`)
+	}))
+}
+
+func TestAppEnvImport(t *testing.T) {
+	var envs apiTypes.Envs
+	mockServer := envImportMockServer(t, &envs)
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+	tsuruCtx.Viper.Set("disable-colors", true)
+	tsuruCtx.Stdin = &tsuructx.FakeStdin{Reader: strings.NewReader("y\n")}
+	afero.WriteFile(tsuruCtx.Fs, ".env", []byte("DEBUG=1\nTOKEN=xyz\nKEEP=1\nNEW=value\n"), 0644)
+
+	cmd := newAppEnvImportCmd(tsuruCtx)
+	cmd.Flags().Parse([]string{"-f", ".env", "--no-restart"})
+	err := appEnvImportCmdRun(tsuruCtx, cmd, []string{"myapp"})
+	assert.NoError(t, err)
+	expected := `Changes to the environment variables of app "myapp":
+  ~ DEBUG: 0 -> 1
+  + NEW=value
+  ~ TOKEN (private, value changed)
+Apply these changes to app "myapp"? (y/N) ---- Setting 3 new environment variables ----
+`
+	assert.Equal(t, expected, tsuruCtx.Stdout.(*strings.Builder).String())
+	public, private := false, true
+	assert.Equal(t, apiTypes.Envs{
+		Envs: []apiTypes.Env{
+			{Name: "DEBUG", Value: "1", Private: &public},
+			{Name: "NEW", Value: "value", Private: &public},
+			{Name: "TOKEN", Value: "xyz", Private: &private},
+		},
+		NoRestart: true,
+	}, envs)
+}
+
+func TestAppEnvImportNotConfirmed(t *testing.T) {
+	var envs apiTypes.Envs
+	mockServer := envImportMockServer(t, &envs)
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+	tsuruCtx.Viper.Set("disable-colors", true)
+	tsuruCtx.Stdin = &tsuructx.FakeStdin{Reader: strings.NewReader("n\n")}
+	afero.WriteFile(tsuruCtx.Fs, ".env", []byte("NEW=value\n"), 0644)
+
+	cmd := newAppEnvImportCmd(tsuruCtx)
+	cmd.Flags().Parse([]string{"-a", "myapp", "-f", ".env", "--private"})
+	err := appEnvImportCmdRun(tsuruCtx, cmd, nil)
+	assert.NoError(t, err)
+	expected := `Changes to the environment variables of app "myapp":
+  + NEW=*** (private variable)
+Apply these changes to app "myapp"? (y/N) `
+	assert.Equal(t, expected, tsuruCtx.Stdout.(*strings.Builder).String())
+	assert.Nil(t, envs.Envs)
+}
+
+func TestAppEnvImportNoChanges(t *testing.T) {
+	var envs apiTypes.Envs
+	mockServer := envImportMockServer(t, &envs)
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+	tsuruCtx.Stdin = &tsuructx.FakeStdin{Reader: strings.NewReader("KEEP=1\nDEBUG=0\n")}
+
+	cmd := newAppEnvImportCmd(tsuruCtx)
+	cmd.Flags().Parse([]string{"-f", "-", "-y"})
+	err := appEnvImportCmdRun(tsuruCtx, cmd, []string{"myapp"})
+	assert.NoError(t, err)
+	assert.Equal(t, "No changes to the environment variables of app \"myapp\".\n", tsuruCtx.Stdout.(*strings.Builder).String())
+}
+
+func TestAppEnvImportInvalid(t *testing.T) {
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	afero.WriteFile(tsuruCtx.Fs, "tsuru.env", []byte("TSURU_APPNAME=other\n"), 0644)
+	afero.WriteFile(tsuruCtx.Fs, "masked.env", []byte("TOKEN=*** (private variable)\n"), 0644)
+	for _, test := range []struct {
+		flags    []string
+		expected string
+	}{
+		{nil, "no file was provided. Please use the --file flag"},
+		{[]string{"-f", "-"}, "--yes is required to read the variables from stdin"},
+		{[]string{"-f", "tsuru.env"}, `invalid file "tsuru.env": TSURU_APPNAME is set by tsuru and can't be imported`},
+		{[]string{"-f", "masked.env"}, `invalid file "masked.env": the value of TOKEN is masked, export it with --show-private`},
+	} {
+		cmd := newAppEnvImportCmd(tsuruCtx)
+		cmd.Flags().Parse(test.flags)
+		err := appEnvImportCmdRun(tsuruCtx, cmd, []string{"myapp"})
+		assert.EqualError(t, err, test.expected)
+	}
+}
@@ -0,0 +1,220 @@
+// Copyright © 2023 tsuru-client authors
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+	"github.com/tsuru/tsuru-client/v2/internal/tsuructx"
+	"github.com/tsuru/tsuru-client/v2/pkg/printer"
+	apiTypes "github.com/tsuru/tsuru/types/api"
+	bindTypes "github.com/tsuru/tsuru/types/bind"
+)
+
+func newAppEnvSyncCmd(tsuruCtx *tsuructx.TsuruContext) *cobra.Command {
+	appEnvSyncCmd := &cobra.Command{
+		Use:   "sync --from APP[@TARGET] --to APP[@TARGET]",
+		Short: "syncs the environment variables of an app to another one",
+		Long: `Makes the environment variables of an app (--to) the same as the ones of
+another app (--from): the missing variables are added, the ones with other
+values are changed and the ones only on --to are removed. The values of the
+private variables of --from are copied too, and they stay private on --to. They
+are masked in the changes shown.
+
+Each app may be on another target, given by its label or URL after the name of
+the app (eg: myapp@production). Apps without a target are on the current one.
+
+The variables synced may be narrowed with [[--only]] and [[--exclude]], using
+glob patterns (eg: --only 'DB_*' --exclude '*_PASSWORD'). The variables set by
+tsuru (eg: TSURU_APPNAME) and by the services bound to either app are never
+synced: each app keeps the ones of its own service instances.
+
+The changes are shown before being applied, asking for confirmation unless
+[[--yes]] is given.`,
+		Example: `$ tsuru app env sync --from myapp-staging --to myapp
+$ tsuru app env sync --from myapp@staging --to myapp@production --exclude 'DATABASE_*'`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return appEnvSyncCmdRun(tsuruCtx, cmd, args)
+		},
+		Args: cobra.NoArgs,
+	}
+
+	appEnvSyncCmd.Flags().String("from", "", "The app whose variables are copied, as APP[@TARGET]")
+	appEnvSyncCmd.Flags().String("to", "", "The app whose variables are changed, as APP[@TARGET]")
+	appEnvSyncCmd.Flags().StringSlice("only", nil, "Sync only the variables matching the glob pattern (may be repeated)")
+	appEnvSyncCmd.Flags().StringSlice("exclude", nil, "Don't sync the variables matching the glob pattern (may be repeated)")
+	appEnvSyncCmd.Flags().BoolP("yes", "y", false, "Don't ask for confirmation")
+	addEnvRestartFlags(appEnvSyncCmd)
+	return appEnvSyncCmd
+}
+
+func appEnvSyncCmdRun(tsuruCtx *tsuructx.TsuruContext, cmd *cobra.Command, args []string) error {
+	from, to := cmd.Flag("from").Value.String(), cmd.Flag("to").Value.String()
+	if from == "" || to == "" {
+		return fmt.Errorf("both --from and --to must be provided")
+	}
+	if from == to {
+		return fmt.Errorf("--from and --to must be different apps")
+	}
+	only, _ := cmd.Flags().GetStringSlice("only")
+	exclude, _ := cmd.Flags().GetStringSlice("exclude")
+	for _, pattern := range append(append([]string{}, only...), exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+	process, noRestart, err := envRestartFlags(cmd)
+	if err != nil {
+		return err
+	}
+	cmd.SilenceUsage = true
+
+	refs := []string{from, to}
+	ctxs := make([]*tsuructx.TsuruContext, len(refs))
+	appNames := make([]string, len(refs))
+	for i, ref := range refs {
+		if ctxs[i], appNames[i], err = appRefContext(tsuruCtx, ref); err != nil {
+			return err
+		}
+	}
+	var wg sync.WaitGroup
+	envs := make([][]bindTypes.EnvVar, len(refs))
+	errs := make([]error, len(refs))
+	for i := range refs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			envs[i], errs[i] = getAppEnvs(ctxs[i], appNames[i])
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	serviceEnvs := serviceEnvNames(envs[0])
+	for name := range serviceEnvNames(envs[1]) {
+		serviceEnvs[name] = true
+	}
+	synced := func(name string) bool {
+		return !printer.Contains(tsuruEnvs, name) && !serviceEnvs[name] && (len(only) == 0 || matchAny(only, name)) && !matchAny(exclude, name)
+	}
+	changes, masked := syncEnvChanges(envs[0], envs[1], synced)
+	if len(masked) > 0 {
+		fmt.Fprintf(tsuruCtx.Stderr, "WARNING: the private variables %s of %s came without their values, they are left unchanged on %s.\n", strings.Join(masked, ", "), from, to)
+	}
+	if len(changes) == 0 {
+		fmt.Fprintf(tsuruCtx.Stdout, "The environment variables of %s are already in sync with %s.\n", to, from)
+		return nil
+	}
+	colorify := printer.Colorify{DisableColors: tsuruCtx.Viper.IsSet("disable-colors")}
+	fmt.Fprintf(tsuruCtx.Stdout, "Changes to the environment variables of %s, from %s:\n", to, from)
+	printEnvChanges(tsuruCtx.Stdout, colorify, changes)
+	if yes, _ := cmd.Flags().GetBool("yes"); !yes && !confirm(tsuruCtx, fmt.Sprintf("Apply these changes to %s?", to)) {
+		return nil
+	}
+
+	var removed []string
+	for _, c := range changes {
+		if c.Kind == envRemoved {
+			removed = append(removed, c.Name)
+		}
+	}
+	// the app is restarted only once, after the last request
+	if toSet := envChangesToSet(changes); len(toSet) > 0 {
+		setProcess := process
+		if len(removed) > 0 {
+			setProcess = ""
+		}
+		if err = setAppEnvs(ctxs[1], appNames[1], apiTypes.Envs{Envs: toSet, NoRestart: noRestart || len(removed) > 0}, setProcess); err != nil {
+			return err
+		}
+	}
+	if len(removed) > 0 {
+		return unsetAppEnvs(ctxs[1], appNames[1], removed, noRestart, process)
+	}
+	return nil
+}
+
+// syncEnvChanges returns the changes making the synced variables of current
+// the same as the ones of source, sorted by name, and the names of the source
+// variables whose values are masked, which are left as they are. tsuru returns
+// the values of private variables, so these are only expected from a server
+// masking them.
+func syncEnvChanges(source, current []bindTypes.EnvVar, synced func(name string) bool) ([]envChange, []string) {
+	byName := map[string]bindTypes.EnvVar{}
+	for _, env := range current {
+		byName[env.Name] = env
+	}
+	var changes []envChange
+	var masked []string
+	for _, env := range source {
+		if !synced(env.Name) {
+			continue
+		}
+		cur, exists := byName[env.Name]
+		delete(byName, env.Name)
+		switch {
+		case !env.Public && env.Value == privateEnvMask:
+			masked = append(masked, env.Name)
+		case !exists:
+			changes = append(changes, envChange{Name: env.Name, After: env.Value, Kind: envAdded, Private: !env.Public})
+		case cur.Value != env.Value || cur.Public != env.Public:
+			before := cur.Value
+			if !cur.Public && env.Public && cur.Value != env.Value {
+				before = privateEnvMask // the old value stays hidden when it becomes public
+			}
+			changes = append(changes, envChange{Name: env.Name, Before: before, After: env.Value, Kind: envChanged, Private: !env.Public})
+		}
+	}
+	for name, cur := range byName {
+		if synced(name) {
+			changes = append(changes, envChange{Name: name, Before: cur.Value, Kind: envRemoved, Private: !cur.Public})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Name < changes[j].Name })
+	sort.Strings(masked)
+	return changes, masked
+}
+
+// serviceEnvNames returns the names of the variables set by the services bound
+// to the app, listed by tsuru in TSURU_SERVICES.
+func serviceEnvNames(envs []bindTypes.EnvVar) map[string]bool {
+	names := map[string]bool{}
+	for _, env := range envs {
+		if env.Name != "TSURU_SERVICES" {
+			continue
+		}
+		var services map[string][]struct {
+			Envs map[string]string `json:"envs"`
+		}
+		json.Unmarshal([]byte(env.Value), &services)
+		for _, instances := range services {
+			for _, instance := range instances {
+				for name := range instance.Envs {
+					names[name] = true
+				}
+			}
+		}
+	}
+	return names
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,215 @@
+// Copyright © 2023 tsuru-client authors
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/tsuru/tsuru-client/v2/internal/config"
+	"github.com/tsuru/tsuru-client/v2/internal/tsuructx"
+	apiTypes "github.com/tsuru/tsuru/types/api"
+	bindTypes "github.com/tsuru/tsuru/types/bind"
+)
+
+const (
+	envSyncFromJSON = `[{"name":"TSURU_APPNAME","value":"myapp-stg","public":true},{"name":"DEBUG","value":"1","public":true},` +
+		`{"name":"TOKEN","value":"stg","public":false},{"name":"NEW","value":"x","public":true},{"name":"SECRET","value":"s","public":false},` +
+		`{"name":"SAME","value":"1","public":true}]`
+	envSyncToJSON = `[{"name":"TSURU_APPNAME","value":"myapp","public":true},{"name":"DEBUG","value":"0","public":true},` +
+		`{"name":"TOKEN","value":"prod","public":false},{"name":"OLD","value":"y","public":true},{"name":"SAME","value":"1","public":true}]`
+)
+
+// envSyncMockServer serves the variables of the app, recording the variables
+// set and unset and the restarts.
+func envSyncMockServer(t *testing.T, appName, envsJSON string, requests *[]string, envs *apiTypes.Envs) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/1.0/apps/"+appName+"/env":
+			fmt.Fprintln(w, envsJSON)
+		case r.Method == "POST" && r.URL.Path == "/1.0/apps/"+appName+"/env":
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(envs))
+			*requests = append(*requests, "set")
+		case r.Method == "DELETE" && r.URL.Path == "/1.0/apps/"+appName+"/env":
+			*requests = append(*requests, fmt.Sprintf("unset %s noRestart=%s", strings.Join(r.URL.Query()["env"], ","), r.URL.Query().Get("noRestart")))
+		case r.Method == "POST" && r.URL.Path == "/1.0/apps/"+appName+"/restart":
+			r.ParseForm()
+			*requests = append(*requests, "restart "+r.Form.Get("process"))
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+	}))
+}
+
+func TestAppEnvSync(t *testing.T) {
+	var stgRequests, prodRequests []string
+	var stgEnvs, prodEnvs apiTypes.Envs
+	stgServer := envSyncMockServer(t, "myapp-stg", envSyncFromJSON, &stgRequests, &stgEnvs)
+	prodServer := envSyncMockServer(t, "myapp", envSyncToJSON, &prodRequests, &prodEnvs)
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(stgServer.URL)
+	tsuruCtx.Viper.Set("disable-colors", true)
+	tsuruCtx.Stdin = &tsuructx.FakeStdin{Reader: strings.NewReader("y\n")}
+	afero.WriteFile(tsuruCtx.Fs, filepath.Join(config.ConfigPath, "targets"), []byte("prod "+prodServer.URL+"\n"), 0600)
+	afero.WriteFile(tsuruCtx.Fs, filepath.Join(config.ConfigPath, "token.d", "prod"), []byte("prodtoken\n"), 0600)
+
+	cmd := newAppEnvSyncCmd(tsuruCtx)
+	cmd.Flags().Parse([]string{"--from", "myapp-stg", "--to", "myapp@prod", "--process", "web"})
+	err := appEnvSyncCmdRun(tsuruCtx, cmd, nil)
+	assert.NoError(t, err)
+	expected := `Changes to the environment variables of myapp@prod, from myapp-stg:
+  ~ DEBUG: 0 -> 1
+  + NEW=x
+  - OLD
+  + SECRET=*** (private variable)
+  ~ TOKEN (private, value changed)
+Apply these changes to myapp@prod? (y/N) `
+	assert.Equal(t, expected, tsuruCtx.Stdout.(*strings.Builder).String())
+	assert.Nil(t, stgRequests)
+	assert.Equal(t, []string{"set", "unset OLD noRestart=true", "restart web"}, prodRequests)
+	public, private := false, true
+	assert.Equal(t, apiTypes.Envs{
+		Envs: []apiTypes.Env{
+			{Name: "DEBUG", Value: "1", Private: &public},
+			{Name: "NEW", Value: "x", Private: &public},
+			{Name: "SECRET", Value: "s", Private: &private},
+			{Name: "TOKEN", Value: "stg", Private: &private},
+		},
+		NoRestart: true,
+	}, prodEnvs)
+}
+
+func TestAppEnvSyncFilters(t *testing.T) {
+	var stgRequests, prodRequests []string
+	var stgEnvs, prodEnvs apiTypes.Envs
+	stgServer := envSyncMockServer(t, "myapp-stg", envSyncFromJSON, &stgRequests, &stgEnvs)
+	prodServer := envSyncMockServer(t, "myapp", envSyncToJSON, &prodRequests, &prodEnvs)
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(prodServer.URL)
+	tsuruCtx.Viper.Set("disable-colors", true)
+	afero.WriteFile(tsuruCtx.Fs, filepath.Join(config.ConfigPath, "targets"), []byte("stg "+stgServer.URL+"\n"), 0600)
+	afero.WriteFile(tsuruCtx.Fs, filepath.Join(config.ConfigPath, "token.d", "stg"), []byte("stgtoken\n"), 0600)
+
+	cmd := newAppEnvSyncCmd(tsuruCtx)
+	cmd.Flags().Parse([]string{"--from", "myapp-stg@stg", "--to", "myapp", "--only", "*E*", "--exclude", "SECRET,SAME", "-y"})
+	err := appEnvSyncCmdRun(tsuruCtx, cmd, nil)
+	assert.NoError(t, err)
+	expected := `Changes to the environment variables of myapp, from myapp-stg@stg:
+  ~ DEBUG: 0 -> 1
+  + NEW=x
+  ~ TOKEN (private, value changed)
+`
+	assert.Equal(t, expected, tsuruCtx.Stdout.(*strings.Builder).String())
+	assert.Equal(t, []string{"set"}, prodRequests)
+	assert.False(t, prodEnvs.NoRestart)
+	assert.Len(t, prodEnvs.Envs, 3)
+}
+
+func TestAppEnvSyncNoChanges(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "GET", r.Method)
+		fmt.Fprintln(w, `[{"name":"DEBUG","value":"1","public":true}]`)
+	}))
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+
+	cmd := newAppEnvSyncCmd(tsuruCtx)
+	cmd.Flags().Parse([]string{"--from", "app1", "--to", "app2"})
+	err := appEnvSyncCmdRun(tsuruCtx, cmd, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "The environment variables of app2 are already in sync with app1.\n", tsuruCtx.Stdout.(*strings.Builder).String())
+}
+
+func TestSyncEnvChangesPrivacy(t *testing.T) {
+	source := []bindTypes.EnvVar{{Name: "A", Value: "1", Public: true}, {Name: "B", Value: "2", Public: false}, {Name: "C", Value: "new", Public: true}}
+	current := []bindTypes.EnvVar{{Name: "A", Value: "1", Public: false}, {Name: "B", Value: "2", Public: true}, {Name: "C", Value: "old", Public: false}}
+	changes, masked := syncEnvChanges(source, current, func(string) bool { return true })
+	assert.Equal(t, []envChange{
+		{Name: "A", Before: "1", After: "1", Kind: envChanged},
+		{Name: "B", Before: "2", After: "2", Kind: envChanged, Private: true},
+		{Name: "C", Before: privateEnvMask, After: "new", Kind: envChanged},
+	}, changes)
+	assert.Nil(t, masked)
+}
+
+func TestAppEnvSyncMaskedPrivateValues(t *testing.T) {
+	var stgRequests, prodRequests []string
+	var stgEnvs, prodEnvs apiTypes.Envs
+	stgServer := envSyncMockServer(t, "myapp-stg", `[{"name":"DEBUG","value":"1","public":true},`+
+		`{"name":"TOKEN","value":"*** (private variable)","public":false},{"name":"SECRET","value":"*** (private variable)","public":false}]`,
+		&stgRequests, &stgEnvs)
+	prodServer := envSyncMockServer(t, "myapp", `[{"name":"DEBUG","value":"0","public":true},{"name":"TOKEN","value":"*** (private variable)","public":false}]`,
+		&prodRequests, &prodEnvs)
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(stgServer.URL)
+	tsuruCtx.Viper.Set("disable-colors", true)
+	afero.WriteFile(tsuruCtx.Fs, filepath.Join(config.ConfigPath, "targets"), []byte("prod "+prodServer.URL+"\n"), 0600)
+	afero.WriteFile(tsuruCtx.Fs, filepath.Join(config.ConfigPath, "token.d", "prod"), []byte("prodtoken\n"), 0600)
+
+	cmd := newAppEnvSyncCmd(tsuruCtx)
+	cmd.Flags().Parse([]string{"--from", "myapp-stg", "--to", "myapp@prod", "-y"})
+	err := appEnvSyncCmdRun(tsuruCtx, cmd, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "WARNING: the private variables SECRET, TOKEN of myapp-stg came without their values, they are left unchanged on myapp@prod.\n",
+		tsuruCtx.Stderr.(*strings.Builder).String())
+	assert.Equal(t, "Changes to the environment variables of myapp@prod, from myapp-stg:\n  ~ DEBUG: 0 -> 1\n", tsuruCtx.Stdout.(*strings.Builder).String())
+	assert.Equal(t, []string{"set"}, prodRequests)
+	public := false
+	assert.Equal(t, []apiTypes.Env{{Name: "DEBUG", Value: "1", Private: &public}}, prodEnvs.Envs)
+}
+
+func TestAppEnvSyncSkipsServiceEnvs(t *testing.T) {
+	var stgRequests, prodRequests []string
+	var stgEnvs, prodEnvs apiTypes.Envs
+	stgServer := envSyncMockServer(t, "myapp-stg", `[{"name":"DEBUG","value":"1","public":true},`+
+		`{"name":"DATABASE_URL","value":"mysql://stg","public":false},{"name":"MONGO_URI","value":"mongodb://stg","public":false},`+
+		`{"name":"TSURU_SERVICES","value":"{\"mysql\":[{\"instance_name\":\"db-stg\",\"envs\":{\"DATABASE_URL\":\"mysql://stg\"}}],`+
+		`\"mongodb\":[{\"instance_name\":\"mongo-stg\",\"envs\":{\"MONGO_URI\":\"mongodb://stg\"}}]}","public":false}]`,
+		&stgRequests, &stgEnvs)
+	prodServer := envSyncMockServer(t, "myapp", `[{"name":"DEBUG","value":"0","public":true},`+
+		`{"name":"DATABASE_URL","value":"mysql://prod","public":false},{"name":"REDIS_URL","value":"redis://prod","public":false},`+
+		`{"name":"TSURU_SERVICES","value":"{\"mysql\":[{\"instance_name\":\"db-prod\",\"envs\":{\"DATABASE_URL\":\"mysql://prod\"}}],`+
+		`\"redis\":[{\"instance_name\":\"cache\",\"envs\":{\"REDIS_URL\":\"redis://prod\"}}]}","public":false}]`,
+		&prodRequests, &prodEnvs)
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(stgServer.URL)
+	tsuruCtx.Viper.Set("disable-colors", true)
+	afero.WriteFile(tsuruCtx.Fs, filepath.Join(config.ConfigPath, "targets"), []byte("prod "+prodServer.URL+"\n"), 0600)
+	afero.WriteFile(tsuruCtx.Fs, filepath.Join(config.ConfigPath, "token.d", "prod"), []byte("prodtoken\n"), 0600)
+
+	cmd := newAppEnvSyncCmd(tsuruCtx)
+	cmd.Flags().Parse([]string{"--from", "myapp-stg", "--to", "myapp@prod", "-y"})
+	err := appEnvSyncCmdRun(tsuruCtx, cmd, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "Changes to the environment variables of myapp@prod, from myapp-stg:\n  ~ DEBUG: 0 -> 1\n", tsuruCtx.Stdout.(*strings.Builder).String())
+	assert.Equal(t, []string{"set"}, prodRequests)
+	public := false
+	assert.Equal(t, []apiTypes.Env{{Name: "DEBUG", Value: "1", Private: &public}}, prodEnvs.Envs)
+}
+
+func TestAppEnvSyncInvalid(t *testing.T) {
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	for _, test := range []struct {
+		flags    []string
+		expected string
+	}{
+		{[]string{"--from", "app1"}, "both --from and --to must be provided"},
+		{[]string{"--from", "app1", "--to", "app1"}, "--from and --to must be different apps"},
+		{[]string{"--from", "app1", "--to", "app2", "--only", "["}, `invalid pattern "[": syntax error in pattern`},
+		{[]string{"--from", "app1", "--to", "app2", "--process", "web", "--no-restart"}, "either use --process or --no-restart, not both"},
+	} {
+		cmd := newAppEnvSyncCmd(tsuruCtx)
+		cmd.Flags().Parse(test.flags)
+		err := appEnvSyncCmdRun(tsuruCtx, cmd, nil)
+		assert.EqualError(t, err, test.expected)
+	}
+}
@@ -0,0 +1,169 @@
+// Copyright © 2023 tsuru-client authors
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tsuru/tsuru-client/v2/internal/tsuructx"
+	apiTypes "github.com/tsuru/tsuru/types/api"
+)
+
+const appEnvs = `[{"name":"DEBUG","value":"1","public":true},{"name":"DATABASE_PASSWORD","value":"s3cr3t","public":false},{"name":"TSURU_APPNAME","value":"myapp","public":true}]`
+
+func TestAppEnvGet(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/1.0/apps/myapp/env", r.URL.Path)
+		fmt.Fprintln(w, appEnvs)
+	}))
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+
+	cmd := newAppEnvGetCmd(tsuruCtx)
+	err := appEnvGetCmdRun(tsuruCtx, cmd, []string{"myapp"})
+	assert.NoError(t, err)
+	expected := `DATABASE_PASSWORD=*** (private variable)
+DEBUG=1
+TSURU_APPNAME=myapp
+`
+	assert.Equal(t, expected, tsuruCtx.Stdout.(*strings.Builder).String())
+}
+
+func TestAppEnvGetShowPrivateJSON(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, []string{"DATABASE_PASSWORD", "DEBUG"}, r.URL.Query()["env"])
+		fmt.Fprintln(w, `[{"name":"DEBUG","value":"1","public":true},{"name":"DATABASE_PASSWORD","value":"s3cr3t","public":false}]`)
+	}))
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+
+	cmd := newAppEnvGetCmd(tsuruCtx)
+	cmd.Flags().Parse([]string{"-a", "myapp", "--show-private", "-o", "json"})
+	err := appEnvGetCmdRun(tsuruCtx, cmd, []string{"DATABASE_PASSWORD", "DEBUG"})
+	assert.NoError(t, err)
+	var views []envVarView
+	assert.NoError(t, json.Unmarshal([]byte(tsuruCtx.Stdout.(*strings.Builder).String()), &views))
+	assert.Equal(t, []envVarView{
+		{Name: "DATABASE_PASSWORD", Value: "s3cr3t", Private: true},
+		{Name: "DEBUG", Value: "1"},
+	}, views)
+}
+
+func TestAppEnvSet(t *testing.T) {
+	var envs apiTypes.Envs
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "POST", r.Method)
+		assert.Equal(t, "/1.0/apps/myapp/env", r.URL.Path)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&envs))
+		fmt.Fprintln(w, `{"Message":"---- Setting 2 new environment variables ----\n"}`)
+	}))
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+
+	cmd := newAppEnvSetCmd(tsuruCtx)
+	cmd.Flags().Parse([]string{"--private", "--no-restart"})
+	err := appEnvSetCmdRun(tsuruCtx, cmd, []string{"myapp", "PASSWORD=a=b", "EMPTY="})
+	assert.NoError(t, err)
+	assert.Equal(t, apiTypes.Envs{
+		Envs:      []apiTypes.Env{{Name: "PASSWORD", Value: "a=b"}, {Name: "EMPTY", Value: ""}},
+		Private:   true,
+		NoRestart: true,
+	}, envs)
+	assert.Equal(t, "---- Setting 2 new environment variables ----\n", tsuruCtx.Stdout.(*strings.Builder).String())
+}
+
+func TestAppEnvSetProcess(t *testing.T) {
+	var calls []string
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		switch r.URL.Path {
+		case "/1.0/apps/myapp/env":
+			var envs apiTypes.Envs
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&envs))
+			assert.True(t, envs.NoRestart)
+		case "/1.0/apps/myapp/restart":
+			assert.NoError(t, r.ParseForm())
+			assert.Equal(t, "worker", r.PostForm.Get("process"))
+			fmt.Fprintln(w, `{"Message":"restarting worker\n"}`)
+		}
+	}))
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+
+	cmd := newAppEnvSetCmd(tsuruCtx)
+	cmd.Flags().Parse([]string{"-a", "myapp", "--process", "worker"})
+	err := appEnvSetCmdRun(tsuruCtx, cmd, []string{"QUEUE_SIZE=100"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"POST /1.0/apps/myapp/env", "POST /1.0/apps/myapp/restart"}, calls)
+	assert.Equal(t, "restarting worker\n", tsuruCtx.Stdout.(*strings.Builder).String())
+}
+
+func TestAppEnvSetInvalidArgs(t *testing.T) {
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	for _, test := range []struct {
+		flags    []string
+		args     []string
+		expected string
+	}{
+		{nil, []string{"myapp"}, "no environment variable was provided, they must be in the form NAME=value"},
+		{nil, []string{"myapp", "DEBUG"}, `invalid environment variable "DEBUG", it must be in the form NAME=value`},
+		{[]string{"-a", "myapp"}, []string{"=1"}, `invalid environment variable "=1", it must be in the form NAME=value`},
+		{[]string{"--process", "web", "--no-restart"}, []string{"myapp", "A=1"}, "either use --process or --no-restart, not both"},
+	} {
+		cmd := newAppEnvSetCmd(tsuruCtx)
+		cmd.Flags().Parse(test.flags)
+		err := appEnvSetCmdRun(tsuruCtx, cmd, test.args)
+		assert.EqualError(t, err, test.expected)
+	}
+}
+
+func TestAppEnvUnset(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "DELETE", r.Method)
+		assert.Equal(t, "/1.0/apps/myapp/env", r.URL.Path)
+		assert.Equal(t, url.Values{"env": {"DEBUG", "WORKERS"}, "noRestart": {"true"}}, r.URL.Query())
+		fmt.Fprintln(w, `{"Message":"---- Unsetting 2 environment variables ----\n"}`)
+	}))
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+
+	cmd := newAppEnvUnsetCmd(tsuruCtx)
+	cmd.Flags().Parse([]string{"--no-restart"})
+	err := appEnvUnsetCmdRun(tsuruCtx, cmd, []string{"myapp", "DEBUG", "WORKERS"})
+	assert.NoError(t, err)
+	assert.Equal(t, "---- Unsetting 2 environment variables ----\n", tsuruCtx.Stdout.(*strings.Builder).String())
+}
+
+func TestAppEnvUnsetServerError(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "You must provide the list of environment variables.", http.StatusBadRequest)
+	}))
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+
+	cmd := newAppEnvUnsetCmd(tsuruCtx)
+	err := appEnvUnsetCmdRun(tsuruCtx, cmd, []string{"myapp", ""})
+	assert.EqualError(t, err, `failed to unset the environment variables of app "myapp": You must provide the list of environment variables.`)
+}
+
+func TestAppEnvIsRegistered(t *testing.T) {
+	appCmd := NewAppCmd(tsuructx.TsuruContextWithConfig(nil))
+	envCmd, _, err := appCmd.Find([]string{"env"})
+	if assert.NoError(t, err) && assert.Equal(t, "env", envCmd.Name()) {
+		var names []string
+		for _, subCmd := range envCmd.Commands() {
+			names = append(names, subCmd.Name())
+		}
+		assert.ElementsMatch(t, []string{"get", "set", "unset", "export", "import", "sync"}, names)
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"text/template"
 	"time"
 
@@ -20,6 +21,7 @@ import (
 	"github.com/tsuru/tablecli"
 	"github.com/tsuru/tsuru-client/v2/internal/parser"
 	"github.com/tsuru/tsuru-client/v2/internal/tsuructx"
+	"github.com/tsuru/tsuru-client/v2/internal/watch"
 	"github.com/tsuru/tsuru-client/v2/pkg/cmd/plan"
 	"github.com/tsuru/tsuru-client/v2/pkg/cmd/router"
 	"github.com/tsuru/tsuru-client/v2/pkg/printer"
@@ -90,72 +92,200 @@ Quota: {{ .QuotaString }}
 
 func newAppInfoCmd(tsuruCtx *tsuructx.TsuruContext) *cobra.Command {
 	appInfoCmd := &cobra.Command{
-		Use:   "info [APP]",
-		Short: "shows information about a specific app",
-		Long: `shows information about a specific app.
+		Use:   "info [APP...]",
+		Short: "shows information about apps",
+		Long: `shows information about apps.
 Its name, platform, state (and its units), address, etc.
 You need to be a member of a team that has access to the app to be able to see information about it.
+
+Many apps may be given as arguments, or selected by their tags with [[--tag]].
+They are fetched in parallel and shown one after the other, separated by a
+"---" line. With the json or yaml output, they are shown as a single list
+(even when [[--tag]] matches a single app).
+
+The table output may be narrowed to some sections with [[--section]], or some
+sections may be left out with [[--hide]]. The sections are: app (name,
+platform, teams, etc.), units, services, autoscale, plan, internal-addresses,
+routers and volumes.
 `,
 		Example: `$ tsuru app info myapp
-$ tsuru app info -a myapp`,
+$ tsuru app info -a myapp
+$ tsuru app info myapp otherapp
+$ tsuru app info --tag team:payments -o json
+$ tsuru app info myapp --section units,routers
+$ tsuru app info myapp --hide volumes,services`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return printAppInfo(tsuruCtx, cmd, args)
 		},
 		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 			return completeAppNames(tsuruCtx, cmd, args, toComplete)
 		},
-		Args: cobra.RangeArgs(0, 1),
+		Args:        cobra.ArbitraryArgs,
+		Annotations: map[string]string{watch.Annotation: "true"},
 	}
 
 	appInfoCmd.Flags().StringP("app", "a", "", "The name of the app (may be passed as argument)")
+	appInfoCmd.Flags().StringSliceP("tag", "g", []string{}, "show the apps with the tag. Can be used multiple times")
 	appInfoCmd.Flags().BoolP("simplified", "s", false, "Show simplified view of app")
 	appInfoCmd.Flags().Bool("json", false, "Show JSON view of app")
+	appInfoCmd.Flags().StringP("output", "o", "table", "Output format: table, json or yaml")
+	appInfoCmd.Flags().StringSlice("section", nil, "Show only the given sections of the table output (eg: units,routers)")
+	appInfoCmd.Flags().StringSlice("hide", nil, "Hide the given sections of the table output (eg: volumes)")
 	return appInfoCmd
 }
 
 func printAppInfo(tsuruCtx *tsuructx.TsuruContext, cmd *cobra.Command, args []string) error {
-	if len(args) == 0 && cmd.Flag("app").Value.String() == "" {
+	tags, _ := cmd.Flags().GetStringSlice("tag")
+	if len(args) == 0 && cmd.Flag("app").Value.String() == "" && len(tags) == 0 {
 		return fmt.Errorf("no app was provided. Please provide an app name or use the --app flag")
 	}
 	if len(args) > 0 && cmd.Flag("app").Value.String() != "" {
 		return fmt.Errorf("either pass an app name as an argument or use the --app flag, not both")
 	}
+	opts, err := appInfoViewOptions(cmd)
+	if err != nil {
+		return err
+	}
 	cmd.SilenceUsage = true
 
-	appName := cmd.Flag("app").Value.String()
-	if appName == "" {
-		appName = args[0]
+	appNames := args
+	if appName := cmd.Flag("app").Value.String(); appName != "" {
+		appNames = []string{appName}
+	}
+	if len(tags) > 0 {
+		tagged, err := appNamesFromQuery(tsuruCtx, appListQueryString(cmd, tsuruCtx))
+		if err != nil {
+			return err
+		}
+		if len(tagged) == 0 && len(appNames) == 0 {
+			return fmt.Errorf("no apps found with the tags: %s", strings.Join(tags, ", "))
+		}
+		appNames = append(appNames, tagged...)
 	}
 
-	request, err := tsuruCtx.NewRequest("GET", "/apps/"+appName, nil)
+	apps, err := getApps(tsuruCtx, appNames)
 	if err != nil {
 		return err
 	}
+
+	format := printer.FormatAs(cmd.Flag("output").Value.String())
+	if v, _ := cmd.Flags().GetBool("json"); v {
+		format = printer.JSON
+	}
+	simplified := cmd.Flag("simplified").Value.String() == "true"
+	if len(appNames) == 1 && len(tags) == 0 {
+		return apps[0].PrintInfo(tsuruCtx.Stdout, format, simplified, opts)
+	}
+	switch format {
+	case printer.JSON:
+		return printer.PrintPrettyJSON(tsuruCtx.Stdout, apps)
+	case printer.YAML:
+		return printer.PrintYAML(tsuruCtx.Stdout, apps)
+	}
+	for i, a := range apps {
+		if i > 0 {
+			fmt.Fprint(tsuruCtx.Stdout, "---\n\n")
+		}
+		if err = a.PrintInfo(tsuruCtx.Stdout, format, simplified, opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// appInfoSections are the sections of the table output of app info, which
+// may be chosen with --section and --hide.
+var appInfoSections = []string{"app", "units", "services", "autoscale", "plan", "internal-addresses", "routers", "volumes"}
+
+// appInfoViewOptions returns the options selecting the sections shown by app
+// info, according to --section and --hide.
+func appInfoViewOptions(cmd *cobra.Command) (*printer.TableViewOptions, error) {
+	sections, _ := cmd.Flags().GetStringSlice("section")
+	hidden, _ := cmd.Flags().GetStringSlice("hide")
+	if len(sections) > 0 && len(hidden) > 0 {
+		return nil, fmt.Errorf("either use --section or --hide, not both")
+	}
+	for _, section := range append(append([]string{}, sections...), hidden...) {
+		if !printer.Contains(appInfoSections, section) {
+			return nil, fmt.Errorf("invalid section %q, it must be one of: %s", section, strings.Join(appInfoSections, ", "))
+		}
+	}
+	return &printer.TableViewOptions{ShowFields: sections, HiddenFields: hidden}, nil
+}
+
+// maxParallelAppFetches is how many apps getApps fetches at the same time.
+const maxParallelAppFetches = 8
+
+// getApps returns the apps in the given order, fetching them in parallel, at
+// most maxParallelAppFetches at a time. Apps given more than once are only
+// fetched (and returned) once.
+func getApps(tsuruCtx *tsuructx.TsuruContext, appNames []string) ([]*app, error) {
+	var unique []string
+	seen := map[string]bool{}
+	for _, name := range appNames {
+		if !seen[name] {
+			seen[name] = true
+			unique = append(unique, name)
+		}
+	}
+
+	var wg sync.WaitGroup
+	apps := make([]*app, len(unique))
+	errs := make([]error, len(unique))
+	semaphore := make(chan struct{}, maxParallelAppFetches)
+	for i, name := range unique {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+			apps[i], errs[i] = getApp(tsuruCtx, name)
+		}(i, name)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return apps, nil
+}
+
+// getApp returns the app, as shown by "app info".
+func getApp(tsuruCtx *tsuructx.TsuruContext, appName string) (*app, error) {
+	request, err := tsuruCtx.NewRequest("GET", "/apps/"+appName, nil)
+	if err != nil {
+		return nil, err
+	}
 	httpResponse, err := tsuruCtx.RawHTTPClient().Do(request)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer httpResponse.Body.Close()
 	if httpResponse.StatusCode == http.StatusNotFound {
-		return fmt.Errorf("app %q not found", appName)
+		return nil, fmt.Errorf("app %q not found", appName)
+	}
+	if httpResponse.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(httpResponse.Body)
+		return nil, fmt.Errorf("failed to get app %q: %s", appName, strings.TrimSpace(string(respBody)))
 	}
 
 	var a app
 	err = json.NewDecoder(httpResponse.Body).Decode(&a)
 	if err != nil {
-		return err
-	}
-
-	format := "table"
-	if v, _ := cmd.Flags().GetBool("json"); v {
-		format = "json"
+		return nil, err
 	}
-	return a.PrintInfo(tsuruCtx.Stdout, printer.FormatAs(format), cmd.Flag("simplified").Value.String() == "true")
+	return &a, nil
 }
 
-func (a *app) PrintInfo(out io.Writer, format printer.OutputType, simplified bool) error {
-	if format == printer.JSON {
+// PrintInfo prints the app. The table output shows only the sections (see
+// appInfoSections) visible on opts, which may be nil to show all of them.
+func (a *app) PrintInfo(out io.Writer, format printer.OutputType, simplified bool, opts *printer.TableViewOptions) error {
+	switch format {
+	case printer.JSON:
 		return printer.PrintPrettyJSON(out, a)
+	case printer.YAML:
+		return printer.PrintYAML(out, a)
 	}
 
 	httpTemplate := fullFormat
@@ -166,10 +296,12 @@ func (a *app) PrintInfo(out io.Writer, format printer.OutputType, simplified boo
 	var buf bytes.Buffer
 	tmpl := template.Must(template.New("app").Parse(httpTemplate))
 
-	if simplified {
-		renderUnitsSummary(&buf, a.Units, a.UnitsMetrics, a.Provisioner)
-	} else {
-		renderUnits(&buf, a.Units, a.UnitsMetrics, a.Provisioner)
+	if opts.IsFieldVisible("units") {
+		if simplified {
+			renderUnitsSummary(&buf, a.Units, a.UnitsMetrics, a.Provisioner)
+		} else {
+			renderUnits(&buf, a.Units, a.UnitsMetrics, a.Provisioner)
+		}
 	}
 
 	internalAddressesTable := tablecli.NewTable()
@@ -183,7 +315,7 @@ func (a *app) PrintInfo(out io.Writer, format printer.OutputType, simplified boo
 		})
 	}
 
-	if !simplified {
+	if !simplified && opts.IsFieldVisible("services") {
 		renderServiceInstanceBinds(&buf, a.ServiceInstanceBinds)
 	}
 
@@ -199,23 +331,23 @@ func (a *app) PrintInfo(out io.Writer, format printer.OutputType, simplified boo
 		}))
 	}
 
-	if autoScaleTable.Rows() > 0 {
+	if autoScaleTable.Rows() > 0 && opts.IsFieldVisible("autoscale") {
 		buf.WriteString("\n")
 		buf.WriteString("Auto Scale:\n")
 		buf.WriteString(autoScaleTable.String())
 	}
 
-	if !simplified && (a.Plan.Memory != 0 || a.Plan.CPUMilli != 0) {
+	if !simplified && (a.Plan.Memory != 0 || a.Plan.CPUMilli != 0) && opts.IsFieldVisible("plan") {
 		buf.WriteString("\n")
 		buf.WriteString("App Plan:\n")
 		buf.WriteString(plan.RenderPlans([]appTypes.Plan{a.Plan}, false, false))
 	}
-	if !simplified && internalAddressesTable.Rows() > 0 {
+	if !simplified && internalAddressesTable.Rows() > 0 && opts.IsFieldVisible("internal-addresses") {
 		buf.WriteString("\n")
 		buf.WriteString("Cluster internal addresses:\n")
 		buf.WriteString(internalAddressesTable.String())
 	}
-	if !simplified && len(a.Routers) > 0 {
+	if !simplified && len(a.Routers) > 0 && opts.IsFieldVisible("routers") {
 		buf.WriteString("\n")
 		if a.Provisioner == "kubernetes" {
 			buf.WriteString("Cluster external addresses:\n")
@@ -226,8 +358,15 @@ func (a *app) PrintInfo(out io.Writer, format printer.OutputType, simplified boo
 		}
 	}
 
-	renderVolumeBinds(&buf, a.VolumeBinds)
+	if opts.IsFieldVisible("volumes") {
+		renderVolumeBinds(&buf, a.VolumeBinds)
+	}
 
+	if !opts.IsFieldVisible("app") {
+		// the sections start with a blank line, separating them from the app
+		fmt.Fprintln(out, strings.TrimLeft(buf.String(), "\n"))
+		return nil
+	}
 	var tplBuffer bytes.Buffer
 	err := tmpl.Execute(&tplBuffer, a)
 	fmt.Fprintln(out, tplBuffer.String()+buf.String())
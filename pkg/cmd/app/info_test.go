@@ -5,15 +5,18 @@
 package app
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/tsuru/tsuru-client/v2/internal/tsuructx"
+	"gopkg.in/yaml.v3"
 )
 
 func TestV1AppInfo(t *testing.T) {
@@ -1321,3 +1324,239 @@ func TestAppInfoIsRegistered(t *testing.T) {
 func TestV1AppListIsACommand(t *testing.T) {
 	TestAppInfoIsRegistered(t)
 }
+
+func appInfoManyAppsServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/1.0/apps":
+			assert.Equal(t, []string{"team:a"}, r.URL.Query()["tag"])
+			assert.Equal(t, "true", r.URL.Query().Get("simplified"))
+			fmt.Fprintln(w, `[{"name":"app1"},{"name":"app2"}]`)
+		case "/1.0/apps/app1", "/1.0/apps/app2":
+			name := strings.TrimPrefix(r.URL.Path, "/1.0/apps/")
+			fmt.Fprintf(w, `{"name":%q,"platform":"go","pool":"dev","owner":"me","teamowner":"myteam","plan":{"name":"small"}}`, name)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestAppInfoManyApps(t *testing.T) {
+	mockServer := appInfoManyAppsServer(t)
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+
+	appInfoCmd := newAppInfoCmd(tsuruCtx)
+	appInfoCmd.Flags().Parse([]string{"-s"})
+	err := printAppInfo(tsuruCtx, appInfoCmd, []string{"app2", "app1", "app2"})
+	assert.NoError(t, err)
+	expected := `Application: app2
+Created by: me
+Platform: go
+Plan: small
+Pool: dev ()
+Router:
+Teams: myteam (owner)
+Units: 0
+
+---
+
+Application: app1
+Created by: me
+Platform: go
+Plan: small
+Pool: dev ()
+Router:
+Teams: myteam (owner)
+Units: 0
+
+`
+	assert.Equal(t, expected, tsuruCtx.Stdout.(*strings.Builder).String())
+}
+
+func TestAppInfoByTagJSON(t *testing.T) {
+	mockServer := appInfoManyAppsServer(t)
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+
+	appInfoCmd := newAppInfoCmd(tsuruCtx)
+	appInfoCmd.Flags().Parse([]string{"--tag", "team:a", "-o", "json"})
+	err := printAppInfo(tsuruCtx, appInfoCmd, nil)
+	assert.NoError(t, err)
+	var apps []app
+	assert.NoError(t, json.Unmarshal([]byte(tsuruCtx.Stdout.(*strings.Builder).String()), &apps))
+	if assert.Len(t, apps, 2) {
+		assert.Equal(t, "app1", apps[0].Name)
+		assert.Equal(t, "app2", apps[1].Name)
+	}
+}
+
+func TestAppInfoByTagYAML(t *testing.T) {
+	mockServer := appInfoManyAppsServer(t)
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+
+	appInfoCmd := newAppInfoCmd(tsuruCtx)
+	appInfoCmd.Flags().Parse([]string{"--tag", "team:a", "-o", "yaml"})
+	err := printAppInfo(tsuruCtx, appInfoCmd, nil)
+	assert.NoError(t, err)
+	stdout := tsuruCtx.Stdout.(*strings.Builder).String()
+	var apps []map[string]any
+	assert.NoError(t, yaml.Unmarshal([]byte(stdout), &apps))
+	if assert.Len(t, apps, 2) {
+		assert.Equal(t, "app1", apps[0]["name"])
+		assert.Equal(t, "app2", apps[1]["name"])
+	}
+}
+
+func TestAppInfoByTagJSONSingleApp(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/1.0/apps":
+			fmt.Fprintln(w, `[{"name":"app1"}]`)
+		case "/1.0/apps/app1":
+			fmt.Fprintln(w, `{"name":"app1","platform":"go"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+
+	appInfoCmd := newAppInfoCmd(tsuruCtx)
+	appInfoCmd.Flags().Parse([]string{"--tag", "team:a", "-o", "json"})
+	err := printAppInfo(tsuruCtx, appInfoCmd, nil)
+	assert.NoError(t, err)
+	var apps []app
+	assert.NoError(t, json.Unmarshal([]byte(tsuruCtx.Stdout.(*strings.Builder).String()), &apps))
+	if assert.Len(t, apps, 1) {
+		assert.Equal(t, "app1", apps[0].Name)
+	}
+}
+
+func TestAppInfoManyAppsNotFound(t *testing.T) {
+	mockServer := appInfoManyAppsServer(t)
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+
+	appInfoCmd := newAppInfoCmd(tsuruCtx)
+	err := printAppInfo(tsuruCtx, appInfoCmd, []string{"app1", "unknown"})
+	assert.EqualError(t, err, `app "unknown" not found`)
+	assert.Equal(t, "", tsuruCtx.Stdout.(*strings.Builder).String())
+}
+
+func TestAppInfoManyAppsServerError(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprintln(w, "invalid token")
+	}))
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+
+	appInfoCmd := newAppInfoCmd(tsuruCtx)
+	err := printAppInfo(tsuruCtx, appInfoCmd, []string{"app1"})
+	assert.EqualError(t, err, `failed to get app "app1": invalid token`)
+}
+
+func TestGetAppsLimitsConcurrency(t *testing.T) {
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+		fmt.Fprintf(w, `{"name":%q}`, strings.TrimPrefix(r.URL.Path, "/1.0/apps/"))
+	}))
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+
+	var names []string
+	for i := 0; i < 3*maxParallelAppFetches; i++ {
+		names = append(names, fmt.Sprintf("app%d", i))
+	}
+	apps, err := getApps(tsuruCtx, names)
+	assert.NoError(t, err)
+	if assert.Len(t, apps, len(names)) {
+		assert.Equal(t, "app5", apps[5].Name)
+	}
+	assert.LessOrEqual(t, maxRunning, maxParallelAppFetches)
+}
+
+const appInfoWithSections = `{"name":"app1","platform":"go","teamowner":"myteam","owner":"me","provisioner":"kubernetes",
+"units":[{"ID":"app1-web-1","Status":"started","ProcessName":"web","Address":{"Host":"10.8.7.6:3333"},"ready":true}],
+"routers":[{"name":"ingress","address":"app1.example.com"}],
+"volumeBinds":[{"ID":{"App":"app1","MountPoint":"/data","Volume":"vol1"},"ReadOnly":false}]}`
+
+func TestAppInfoSections(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, appInfoWithSections)
+	}))
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+
+	appInfoCmd := newAppInfoCmd(tsuruCtx)
+	appInfoCmd.Flags().Parse([]string{"--section", "routers,volumes"})
+	err := printAppInfo(tsuruCtx, appInfoCmd, []string{"app1"})
+	assert.NoError(t, err)
+	expected := `Cluster external addresses:
++---------+------+------------------+--------+
+| Router  | Opts | Addresses        | Status |
++---------+------+------------------+--------+
+| ingress |      | app1.example.com |        |
++---------+------+------------------+--------+
+
+Volumes: 1
++------+------------+------+
+| Name | MountPoint | Mode |
++------+------------+------+
+| vol1 | /data      | rw   |
++------+------------+------+
+
+`
+	assert.Equal(t, expected, tsuruCtx.Stdout.(*strings.Builder).String())
+}
+
+func TestAppInfoHideSections(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, appInfoWithSections)
+	}))
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+
+	appInfoCmd := newAppInfoCmd(tsuruCtx)
+	appInfoCmd.Flags().Parse([]string{"--hide", "units", "--hide", "routers,volumes"})
+	err := printAppInfo(tsuruCtx, appInfoCmd, []string{"app1"})
+	assert.NoError(t, err)
+	expected := `Application: app1
+Platform: go
+Provisioner: kubernetes
+Teams: myteam (owner)
+External Addresses: app1.example.com
+Created by: me
+Deploys: 0
+Pool:
+Quota: 0/0 units
+
+`
+	assert.Equal(t, expected, tsuruCtx.Stdout.(*strings.Builder).String())
+}
+
+func TestAppInfoInvalidSections(t *testing.T) {
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	appInfoCmd := newAppInfoCmd(tsuruCtx)
+	appInfoCmd.Flags().Parse([]string{"--section", "units,metrics"})
+	err := printAppInfo(tsuruCtx, appInfoCmd, []string{"app1"})
+	assert.EqualError(t, err, `invalid section "metrics", it must be one of: app, units, services, autoscale, plan, internal-addresses, routers, volumes`)
+
+	appInfoCmd = newAppInfoCmd(tsuruCtx)
+	appInfoCmd.Flags().Parse([]string{"--section", "units", "--hide", "volumes"})
+	err = printAppInfo(tsuruCtx, appInfoCmd, []string{"app1"})
+	assert.EqualError(t, err, "either use --section or --hide, not both")
+}
@@ -10,12 +10,15 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"reflect"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/tsuru/tablecli"
 	"github.com/tsuru/tsuru-client/v2/internal/tsuructx"
+	"github.com/tsuru/tsuru-client/v2/internal/watch"
 	"github.com/tsuru/tsuru-client/v2/pkg/printer"
 )
 
@@ -25,14 +28,28 @@ func newAppListCmd(tsuruCtx *tsuructx.TsuruContext) *cobra.Command {
 		Short: "list apps",
 		Long: `Lists all apps that you have access to. App access is controlled by teams.
 If your team has access to an app, then you have access to it.
-Flags can be used to filter the list of applications.`,
+Flags can be used to filter the list of applications.
+
+The [[--output]] flag chooses how the apps are shown: table (the default), wide
+(adding platform, pool, plan, team owner, tags and deploys), json, yaml, or
+custom-columns=HEADER:.FIELD,... with the given fields of the apps (eg:
+.Name, .Plan.Name, .Tags). It has no short form, as -o filters by pool. The
+apps are sorted by name, or by the field given to [[--sort-by]].
+
+The [[--group-by]] flag shows, instead of the apps, the number of apps and of
+their units (total, ready and with error) by pool, team (owner), platform,
+plan or status (the status of most units of each app).`,
 		Example: `$ tsuru app list
 $ tsuru app list -n my
-$ tsuru app list --status error`,
+$ tsuru app list --status error
+$ tsuru app list --output wide --sort-by .Deploys
+$ tsuru app list --output custom-columns=NAME:.Name,POOL:.Pool,PLAN:.Plan.Name
+$ tsuru app list --group-by pool`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return appListCmdRun(tsuruCtx, cmd, args)
 		},
-		Args: cobra.ExactArgs(0),
+		Args:        cobra.ExactArgs(0),
+		Annotations: map[string]string{watch.Annotation: "true"},
 	}
 
 	appListCmd.Flags().StringP("name", "n", "", "filter applications by name")
@@ -44,12 +61,44 @@ $ tsuru app list --status error`,
 	appListCmd.Flags().BoolP("locked", "l", false, "filter applications by lock status")
 	appListCmd.Flags().BoolP("simplified", "q", false, "display only applications name")
 	appListCmd.Flags().Bool("json", false, "display applications in JSON format")
+	appListCmd.Flags().String("output", "table", "Output format: table, wide, json, yaml or custom-columns=HEADER:.FIELD,...")
+	appListCmd.Flags().String("group-by", "", "show the number of applications and units by pool, team, platform, plan or status")
+	appListCmd.Flags().String("sort-by", "", "sort applications by the given field (eg: .Pool, .Plan.Name, .Deploys)")
 	appListCmd.Flags().StringSliceP("tag", "g", []string{}, "filter applications by tag. Can be used multiple times")
 
 	return appListCmd
 }
 
 func appListCmdRun(tsuruCtx *tsuructx.TsuruContext, cmd *cobra.Command, args []string) error {
+	output := cmd.Flag("output").Value.String()
+	if cmd.Flag("json").Value.String() == "true" {
+		output = "json"
+	}
+	opts, err := appListViewOptions(output, tsuruCtx.Verbosity())
+	if err != nil {
+		return err
+	}
+	sortBy := cmd.Flag("sort-by").Value.String()
+	if sortBy == "" {
+		sortBy = ".Name"
+	}
+	sortIndex, err := appFieldIndex(sortBy)
+	if err != nil {
+		return fmt.Errorf("invalid value %q for --sort-by: %w", sortBy, err)
+	}
+	groupBy := cmd.Flag("group-by").Value.String()
+	groupKey := appGroupKeys[groupBy]
+	if groupBy != "" {
+		if groupKey == nil {
+			return fmt.Errorf("invalid value %q for --group-by, it must be one of: pool, team, platform, plan or status", groupBy)
+		}
+		if output != "table" && output != "json" && output != "yaml" {
+			return fmt.Errorf("--group-by can't be used with --output %s", output)
+		}
+		if cmd.Flag("simplified").Value.String() == "true" {
+			return fmt.Errorf("--group-by can't be used with --simplified")
+		}
+	}
 	cmd.SilenceUsage = true
 
 	qs := appListQueryString(cmd, tsuruCtx)
@@ -64,6 +113,9 @@ func appListCmdRun(tsuruCtx *tsuructx.TsuruContext, cmd *cobra.Command, args []s
 	}
 	defer httpResponse.Body.Close()
 	if httpResponse.StatusCode == http.StatusNoContent {
+		if pool := cmd.Flag("pool").Value.String(); pool == "wide" || strings.HasPrefix(pool, "custom-columns=") {
+			fmt.Fprintf(tsuruCtx.Stderr, "No apps found on pool %q. Note that -o filters applications by pool, use --output %s to choose the output format.\n", pool, pool)
+		}
 		return nil
 	}
 	var apps []app
@@ -72,20 +124,27 @@ func appListCmdRun(tsuruCtx *tsuructx.TsuruContext, cmd *cobra.Command, args []s
 		return err
 	}
 
-	format := "table"
-	if cmd.Flag("json").Value.String() == "true" {
-		format = "json"
+	sort.SliceStable(apps, func(i, j int) bool {
+		a, b := reflect.ValueOf(apps[i]).FieldByIndex(sortIndex), reflect.ValueOf(apps[j]).FieldByIndex(sortIndex)
+		if c := compareFieldValues(a, b); c != 0 {
+			return c < 0
+		}
+		return apps[i].Name < apps[j].Name
+	})
+	if groupKey != nil {
+		return printAppGroups(tsuruCtx.Stdout, printer.FormatAs(output), groupBy, groupApps(apps, groupKey))
 	}
-
-	return printAppList(tsuruCtx.Stdout, printer.FormatAs(format), cmd.Flag("simplified").Value.String() == "true", tsuruCtx.Verbosity(), apps)
+	return printAppList(tsuruCtx.Stdout, printer.FormatAs(output), cmd.Flag("simplified").Value.String() == "true", apps, opts)
 }
 
-func printAppList(out io.Writer, format printer.OutputType, simplified bool, verbosity int, apps []app) error {
-	if format == printer.JSON {
+func printAppList(out io.Writer, format printer.OutputType, simplified bool, apps []app, opts *printer.TableViewOptions) error {
+	switch format {
+	case printer.JSON:
 		return printer.PrintPrettyJSON(out, apps)
+	case printer.YAML:
+		return printer.PrintYAML(out, apps)
 	}
 
-	table := tablecli.NewTable()
 	if simplified {
 		for _, app := range apps {
 			fmt.Fprintln(out, app.Name)
@@ -93,44 +152,265 @@ func printAppList(out io.Writer, format printer.OutputType, simplified bool, ver
 		return nil
 	}
 
-	table.Headers = tablecli.Row([]string{"Application", "Units", "Address"})
+	table := tablecli.NewTable()
+	table.Headers = tablecli.Row(opts.ShowFields)
 	for _, app := range apps {
-		var summary string
-		if app.Error == "" {
-			unitsStatus := make(map[string]int)
-			for _, unit := range app.Units {
-				if unit.ID != "" {
-					if unit.Ready != nil && *unit.Ready {
-						unitsStatus["ready"]++
-					} else {
-						unitsStatus[unit.Status]++
-					}
-				}
-			}
-			statusText := make([]string, len(unitsStatus))
-			i := 0
-			us := newUnitSorter(unitsStatus)
-			sort.Sort(us)
-			for _, status := range us.Statuses {
-				statusText[i] = fmt.Sprintf("%d %s", unitsStatus[status], status)
-				i++
+		row := make(tablecli.Row, len(opts.ShowFields))
+		for i, field := range opts.ShowFields {
+			row[i] = opts.CustomFieldFunc[field](app)
+		}
+		table.AddRow(row)
+	}
+	table.LineSeparator = true
+	out.Write(table.Bytes())
+	return nil
+}
+
+var (
+	appListFields     = []string{"Application", "Units", "Address"}
+	appListWideFields = append(append([]string{}, appListFields...), "Platform", "Pool", "Plan", "Team owner", "Tags", "Deploys")
+)
+
+// appListViewOptions returns the columns of the table output of app list,
+// with the functions computing their values from an app, according to the
+// output (table, wide or custom-columns=HEADER:.FIELD,...).
+func appListViewOptions(output string, verbosity int) (*printer.TableViewOptions, error) {
+	opts := &printer.TableViewOptions{
+		ShowFields: appListFields,
+		CustomFieldFunc: map[string]printer.CustomFieldFunc{
+			"Application": func(v any) string { return v.(app).Name },
+			"Units":       func(v any) string { return appUnitsSummary(v.(app), verbosity) },
+			"Address":     func(v any) string { return strings.Replace(appAddr(v.(app)), ", ", "\n", -1) },
+			"Platform":    func(v any) string { return v.(app).Platform },
+			"Pool":        func(v any) string { return v.(app).Pool },
+			"Plan":        func(v any) string { return v.(app).Plan.Name },
+			"Team owner":  func(v any) string { return v.(app).TeamOwner },
+			"Tags":        func(v any) string { return strings.Join(v.(app).Tags, "\n") },
+			"Deploys":     func(v any) string { return strconv.FormatUint(uint64(v.(app).Deploys), 10) },
+		},
+	}
+	spec, isCustom := strings.CutPrefix(output, "custom-columns=")
+	switch {
+	case output == "wide":
+		opts.ShowFields = appListWideFields
+		return opts, nil
+	case output == "table" || output == "json" || output == "yaml":
+		return opts, nil
+	case !isCustom:
+		return nil, fmt.Errorf("invalid output %q, it must be one of: table, wide, json, yaml or custom-columns=HEADER:.FIELD,...", output)
+	}
+
+	opts = &printer.TableViewOptions{CustomFieldFunc: map[string]printer.CustomFieldFunc{}}
+	for _, column := range strings.Split(spec, ",") {
+		header, field, _ := strings.Cut(column, ":")
+		if header == "" || !strings.HasPrefix(field, ".") {
+			return nil, fmt.Errorf("invalid custom column %q, it must be HEADER:.FIELD", column)
+		}
+		if _, ok := opts.CustomFieldFunc[header]; ok {
+			return nil, fmt.Errorf("invalid custom column %q: header %q is used by another column", column, header)
+		}
+		index, err := appFieldIndex(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid custom column %q: %w", column, err)
+		}
+		opts.ShowFields = append(opts.ShowFields, header)
+		opts.CustomFieldFunc[header] = func(v any) string {
+			return formatFieldValue(reflect.ValueOf(v).FieldByIndex(index))
+		}
+	}
+	return opts, nil
+}
+
+// appUnitsSummary returns the number of units of the app by status, the
+// statuses with more units first.
+func appUnitsSummary(a app, verbosity int) string {
+	if a.Error != "" {
+		summary := "error fetching units"
+		if verbosity > 0 {
+			summary += fmt.Sprintf(": %s", a.Error)
+		}
+		return summary
+	}
+	us := newUnitSorter(appUnitsStatus(a))
+	sort.Sort(us)
+	statusText := make([]string, len(us.Statuses))
+	for i, status := range us.Statuses {
+		statusText[i] = fmt.Sprintf("%d %s", us.Counts[i], status)
+	}
+	return strings.Join(statusText, "\n")
+}
+
+// appUnitsStatus returns the number of units of the app by status, counting
+// the ready units as "ready".
+func appUnitsStatus(a app) map[string]int {
+	unitsStatus := make(map[string]int)
+	for _, unit := range a.Units {
+		if unit.ID != "" {
+			if unit.Ready != nil && *unit.Ready {
+				unitsStatus["ready"]++
+			} else {
+				unitsStatus[unit.Status]++
 			}
-			summary = strings.Join(statusText, "\n")
-		} else {
-			summary = "error fetching units"
-			if verbosity > 0 {
-				summary += fmt.Sprintf(": %s", app.Error)
+		}
+	}
+	return unitsStatus
+}
+
+// appGroupKeys are the values of --group-by, with the functions returning
+// the group of an app.
+var appGroupKeys = map[string]func(a app) string{
+	"pool":     func(a app) string { return a.Pool },
+	"team":     func(a app) string { return a.TeamOwner },
+	"platform": func(a app) string { return a.Platform },
+	"plan":     func(a app) string { return a.Plan.Name },
+	"status": func(a app) string {
+		// the status with more units (see unitSorter)
+		if a.Error != "" {
+			return "error fetching units"
+		}
+		us := newUnitSorter(appUnitsStatus(a))
+		if us.Len() == 0 {
+			return "no units"
+		}
+		sort.Sort(us)
+		return us.Statuses[0]
+	},
+}
+
+type appGroup struct {
+	Name       string `json:"name" yaml:"name"`
+	Apps       int    `json:"apps" yaml:"apps"`
+	Units      int    `json:"units" yaml:"units"`
+	ReadyUnits int    `json:"readyUnits" yaml:"readyUnits"`
+	ErrorUnits int    `json:"errorUnits" yaml:"errorUnits"`
+}
+
+// groupApps returns the apps aggregated by the group given by key, sorted by
+// the name of the groups.
+func groupApps(apps []app, key func(a app) string) []appGroup {
+	byName := map[string]*appGroup{}
+	for _, a := range apps {
+		name := noneIfEmpty(key(a))
+		group := byName[name]
+		if group == nil {
+			group = &appGroup{Name: name}
+			byName[name] = group
+		}
+		group.Apps++
+		for status, count := range appUnitsStatus(a) {
+			group.Units += count
+			switch status {
+			case "ready":
+				group.ReadyUnits += count
+			case "error":
+				group.ErrorUnits += count
 			}
 		}
-		addrs := strings.Replace(appAddr(app), ", ", "\n", -1)
-		table.AddRow(tablecli.Row([]string{app.Name, summary, addrs}))
 	}
-	table.LineSeparator = true
-	table.Sort()
+	groups := make([]appGroup, 0, len(byName))
+	for _, group := range byName {
+		groups = append(groups, *group)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Name < groups[j].Name })
+	return groups
+}
+
+func printAppGroups(out io.Writer, format printer.OutputType, groupBy string, groups []appGroup) error {
+	switch format {
+	case printer.JSON:
+		return printer.PrintPrettyJSON(out, groups)
+	case printer.YAML:
+		return printer.PrintYAML(out, groups)
+	}
+
+	table := tablecli.NewTable()
+	table.Headers = tablecli.Row([]string{strings.ToUpper(groupBy[:1]) + groupBy[1:], "Apps", "Units", "Ready", "Error"})
+	for _, group := range groups {
+		table.AddRow(tablecli.Row([]string{
+			group.Name,
+			strconv.Itoa(group.Apps),
+			strconv.Itoa(group.Units),
+			strconv.Itoa(group.ReadyUnits),
+			strconv.Itoa(group.ErrorUnits),
+		}))
+	}
 	out.Write(table.Bytes())
 	return nil
 }
 
+// appFieldIndex returns the index of the field of app at path (eg: .Name,
+// .Plan.Name), to be used with reflect.Value.FieldByIndex. The names of the
+// fields are matched ignoring case and the leading dot is optional.
+func appFieldIndex(path string) ([]int, error) {
+	var index []int
+	t := reflect.TypeOf(app{})
+	for _, name := range strings.Split(strings.TrimPrefix(path, "."), ".") {
+		if t.Kind() != reflect.Struct {
+			return nil, fmt.Errorf("apps have no field %q", path)
+		}
+		field, ok := t.FieldByNameFunc(func(fieldName string) bool {
+			return strings.EqualFold(fieldName, name)
+		})
+		if !ok || !field.IsExported() {
+			return nil, fmt.Errorf("apps have no field %q", path)
+		}
+		index = append(index, field.Index...)
+		t = field.Type
+	}
+	return index, nil
+}
+
+func formatFieldValue(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return ""
+		}
+		return formatFieldValue(v.Elem())
+	case reflect.Slice, reflect.Array:
+		values := make([]string, v.Len())
+		for i := range values {
+			values[i] = formatFieldValue(v.Index(i))
+		}
+		return strings.Join(values, ", ")
+	case reflect.Map:
+		values := make([]string, 0, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			values = append(values, fmt.Sprintf("%v=%s", iter.Key().Interface(), formatFieldValue(iter.Value())))
+		}
+		sort.Strings(values)
+		return strings.Join(values, ", ")
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// compareFieldValues compares the values of the same field of two apps:
+// numbers by their value, slices and maps by their length and the other
+// ones as text.
+func compareFieldValues(a, b reflect.Value) int {
+	cmp := func(x, y float64) int {
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	}
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return cmp(float64(a.Int()), float64(b.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return cmp(float64(a.Uint()), float64(b.Uint()))
+	case reflect.Float32, reflect.Float64:
+		return cmp(a.Float(), b.Float())
+	case reflect.Slice, reflect.Map:
+		return cmp(float64(a.Len()), float64(b.Len()))
+	}
+	return strings.Compare(formatFieldValue(a), formatFieldValue(b))
+}
+
 type unitSorter struct {
 	Statuses []string
 	Counts   []int
@@ -188,20 +468,29 @@ func appAddr(a app) string {
 	return strings.Join(allAddrs, ", ")
 }
 
+// appListQueryString returns the query string to filter apps, according to
+// the flags of cmd. Flags not defined on cmd are ignored, so other commands
+// can select apps with a subset of the "app list" flags (e.g. --tag).
 func appListQueryString(cmd *cobra.Command, tsuruCtx *tsuructx.TsuruContext) url.Values {
 	result := make(url.Values)
+	flagValue := func(name string) string {
+		if f := cmd.Flag(name); f != nil {
+			return f.Value.String()
+		}
+		return ""
+	}
 
 	// string flags with the same name as the query string
 	for _, flagName := range []string{"name", "platform", "pool", "status"} {
-		if cmd.Flag(flagName).Value.String() != "" {
-			result.Set(flagName, cmd.Flag(flagName).Value.String())
+		if flagValue(flagName) != "" {
+			result.Set(flagName, flagValue(flagName))
 		}
 	}
-	if cmd.Flag("team").Value.String() != "" {
-		result.Set("teamOwner", cmd.Flag("team").Value.String())
+	if flagValue("team") != "" {
+		result.Set("teamOwner", flagValue("team"))
 	}
-	if cmd.Flag("user").Value.String() != "" {
-		userFlag := cmd.Flag("user").Value.String()
+	if flagValue("user") != "" {
+		userFlag := flagValue("user")
 		result.Set("owner", userFlag)
 		if userFlag == "me" {
 			user, _, err := tsuruCtx.Client().UserApi.UserGet(cmd.Context())
@@ -210,15 +499,17 @@ func appListQueryString(cmd *cobra.Command, tsuruCtx *tsuructx.TsuruContext) url
 			}
 		}
 	}
-	if cmd.Flag("locked").Value.String() == "true" {
+	if flagValue("locked") == "true" {
 		result.Set("locked", "true")
 	}
-	if cmd.Flag("simplified").Value.String() == "true" {
+	if flagValue("simplified") == "true" {
 		result.Set("simplified", "true")
 	}
-	tags, _ := cmd.Flags().GetStringSlice("tag")
-	for _, tag := range tags {
-		result.Add("tag", tag)
+	if cmd.Flag("tag") != nil {
+		tags, _ := cmd.Flags().GetStringSlice("tag")
+		for _, tag := range tags {
+			result.Add("tag", tag)
+		}
 	}
 
 	return result
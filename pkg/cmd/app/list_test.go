@@ -5,6 +5,7 @@
 package app
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -407,3 +408,150 @@ func TestAppListIsRegistered(t *testing.T) {
 	}
 	assert.True(t, found, "subcommand list not registered in appCmd")
 }
+
+const appListForOutputs = `[{"ip":"10.10.10.10","name":"app1","platform":"go","pool":"pool2","teamowner":"team1","tags":["a","b"],"deploys":12,"plan":{"name":"small"},"units":[{"ID":"app1/0","Status":"started"}]},
+{"ip":"10.10.10.11","name":"app2","platform":"python","pool":"pool1","teamowner":"team2","deploys":3,"plan":{"name":"large"},"units":[]}]`
+
+func TestAppListOutputWide(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, appListForOutputs)
+	}))
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+
+	appListCmd := newAppListCmd(tsuruCtx)
+	appListCmd.Flags().Parse([]string{"--output", "wide", "--sort-by", ".Pool"})
+	err := appListCmdRun(tsuruCtx, appListCmd, []string{})
+	assert.NoError(t, err)
+	expected := `+-------------+-----------+-------------+----------+-------+-------+------------+------+---------+
+| Application | Units     | Address     | Platform | Pool  | Plan  | Team owner | Tags | Deploys |
++-------------+-----------+-------------+----------+-------+-------+------------+------+---------+
+| app2        |           | 10.10.10.11 | python   | pool1 | large | team2      |      | 3       |
++-------------+-----------+-------------+----------+-------+-------+------------+------+---------+
+| app1        | 1 started | 10.10.10.10 | go       | pool2 | small | team1      | a    | 12      |
+|             |           |             |          |       |       |            | b    |         |
++-------------+-----------+-------------+----------+-------+-------+------------+------+---------+
+`
+	assert.Equal(t, expected, tsuruCtx.Stdout.(*strings.Builder).String())
+}
+
+func TestAppListOutputCustomColumns(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, appListForOutputs)
+	}))
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+
+	appListCmd := newAppListCmd(tsuruCtx)
+	appListCmd.Flags().Parse([]string{"--output", "custom-columns=NAME:.Name,PLAN:.plan.name,TAGS:.Tags,DEPLOYS:.Deploys", "--sort-by", "deploys"})
+	err := appListCmdRun(tsuruCtx, appListCmd, []string{})
+	assert.NoError(t, err)
+	expected := `+------+-------+------+---------+
+| NAME | PLAN  | TAGS | DEPLOYS |
++------+-------+------+---------+
+| app2 | large |      | 3       |
++------+-------+------+---------+
+| app1 | small | a, b | 12      |
++------+-------+------+---------+
+`
+	assert.Equal(t, expected, tsuruCtx.Stdout.(*strings.Builder).String())
+}
+
+func TestAppListInvalidOutputs(t *testing.T) {
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	for _, test := range []struct {
+		flags    []string
+		expected string
+	}{
+		{[]string{"--output", "xml"}, `invalid output "xml", it must be one of: table, wide, json, yaml or custom-columns=HEADER:.FIELD,...`},
+		{[]string{"--output", "custom-columns=NAME"}, `invalid custom column "NAME", it must be HEADER:.FIELD`},
+		{[]string{"--output", "custom-columns=NAME:.Name,NAME:.Pool"}, `invalid custom column "NAME:.Pool": header "NAME" is used by another column`},
+		{[]string{"--output", "custom-columns=NAME:.Name,X:.Plan.Name.Size"}, `invalid custom column "X:.Plan.Name.Size": apps have no field ".Plan.Name.Size"`},
+		{[]string{"--sort-by", ".Size"}, `invalid value ".Size" for --sort-by: apps have no field ".Size"`},
+	} {
+		appListCmd := newAppListCmd(tsuruCtx)
+		appListCmd.Flags().Parse(test.flags)
+		err := appListCmdRun(tsuruCtx, appListCmd, []string{})
+		assert.EqualError(t, err, test.expected)
+	}
+}
+
+const appListForGroups = `[{"name":"app1","pool":"pool1","units":[{"ID":"app1/0","Status":"started","ready":true},{"ID":"app1/1","Status":"error"}]},
+{"name":"app2","pool":"pool1","units":[{"ID":"app2/0","Status":"started","ready":true},{"ID":"app2/1","Status":"started","ready":true}]},
+{"name":"app3","pool":"pool2","units":[{"ID":"app3/0","Status":"error"},{"ID":"app3/1","Status":"error"},{"ID":"app3/2","Status":"starting"}]},
+{"name":"app4","units":[]}]`
+
+func TestAppListPoolLooksLikeOutput(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "wide", r.URL.Query().Get("pool"))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+
+	appListCmd := newAppListCmd(tsuruCtx)
+	appListCmd.Flags().Parse([]string{"-o", "wide"})
+	err := appListCmdRun(tsuruCtx, appListCmd, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, "", tsuruCtx.Stdout.(*strings.Builder).String())
+	assert.Equal(t, "No apps found on pool \"wide\". Note that -o filters applications by pool, use --output wide to choose the output format.\n", tsuruCtx.Stderr.(*strings.Builder).String())
+}
+
+func TestAppListGroupBy(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, appListForGroups)
+	}))
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+
+	appListCmd := newAppListCmd(tsuruCtx)
+	appListCmd.Flags().Parse([]string{"--group-by", "pool"})
+	err := appListCmdRun(tsuruCtx, appListCmd, []string{})
+	assert.NoError(t, err)
+	expected := `+--------+------+-------+-------+-------+
+| Pool   | Apps | Units | Ready | Error |
++--------+------+-------+-------+-------+
+| (none) | 1    | 0     | 0     | 0     |
+| pool1  | 2    | 4     | 3     | 1     |
+| pool2  | 1    | 3     | 0     | 2     |
++--------+------+-------+-------+-------+
+`
+	assert.Equal(t, expected, tsuruCtx.Stdout.(*strings.Builder).String())
+}
+
+func TestAppListGroupByStatusJSON(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, appListForGroups)
+	}))
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+
+	appListCmd := newAppListCmd(tsuruCtx)
+	appListCmd.Flags().Parse([]string{"--group-by", "status", "--output", "json"})
+	err := appListCmdRun(tsuruCtx, appListCmd, []string{})
+	assert.NoError(t, err)
+	var groups []appGroup
+	assert.NoError(t, json.Unmarshal([]byte(tsuruCtx.Stdout.(*strings.Builder).String()), &groups))
+	assert.Equal(t, []appGroup{
+		{Name: "error", Apps: 2, Units: 5, ReadyUnits: 1, ErrorUnits: 3},
+		{Name: "no units", Apps: 1},
+		{Name: "ready", Apps: 1, Units: 2, ReadyUnits: 2},
+	}, groups)
+}
+
+func TestAppListGroupByInvalid(t *testing.T) {
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	for _, test := range []struct {
+		flags    []string
+		expected string
+	}{
+		{[]string{"--group-by", "owner"}, `invalid value "owner" for --group-by, it must be one of: pool, team, platform, plan or status`},
+		{[]string{"--group-by", "pool", "--output", "wide"}, "--group-by can't be used with --output wide"},
+		{[]string{"--group-by", "pool", "-q"}, "--group-by can't be used with --simplified"},
+	} {
+		appListCmd := newAppListCmd(tsuruCtx)
+		appListCmd.Flags().Parse(test.flags)
+		err := appListCmdRun(tsuruCtx, appListCmd, []string{})
+		assert.EqualError(t, err, test.expected)
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright © 2023 tsuru-client authors
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/tsuru/tsuru-client/v2/internal/tsuructx"
+)
+
+func newAppRemoveCmd(tsuruCtx *tsuructx.TsuruContext) *cobra.Command {
+	appRemoveCmd := &cobra.Command{
+		Use:   "remove [APP]",
+		Short: "remove an app",
+		Long: `Remove an app, with its units. The service instances bound to the app are
+unbound (but not removed) and its volumes are unbound.
+
+What is going to be destroyed is shown first, and the name of the app must be
+typed to confirm. Without a terminal (eg: scripts), --yes is required.`,
+		Example: `$ tsuru app remove myapp
+$ tsuru app remove -a myapp --yes`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return appRemoveCmdRun(tsuruCtx, cmd, args)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return completeAppNames(tsuruCtx, cmd, args, toComplete)
+		},
+		Args: cobra.RangeArgs(0, 1),
+	}
+
+	appRemoveCmd.Flags().StringP("app", "a", "", "The name of the app (may be passed as argument)")
+	appRemoveCmd.Flags().BoolP("yes", "y", false, "Don't ask for confirmation")
+	return appRemoveCmd
+}
+
+func appRemoveCmdRun(tsuruCtx *tsuructx.TsuruContext, cmd *cobra.Command, args []string) error {
+	appName, err := appNameFromArgsOrFlag(cmd, args)
+	if err != nil {
+		return err
+	}
+	yes, _ := cmd.Flags().GetBool("yes")
+	if !yes && !isTerminal(tsuruCtx.Stdin) {
+		return fmt.Errorf("stdin is not a terminal to confirm the removal, use --yes to remove app %q anyway", appName)
+	}
+	cmd.SilenceUsage = true
+
+	a, err := getApp(tsuruCtx, appName)
+	if err != nil {
+		return err
+	}
+	printAppRemoval(tsuruCtx.Stdout, a)
+
+	if !yes {
+		fmt.Fprintf(tsuruCtx.Stdout, "\nType the name of the app to confirm: ")
+		answer, _ := bufio.NewReader(tsuruCtx.Stdin).ReadString('\n')
+		if answer = strings.TrimSpace(answer); answer != appName {
+			return fmt.Errorf("%q doesn't match the app name, app %q was not removed", answer, appName)
+		}
+	}
+
+	request, err := tsuruCtx.NewRequest("DELETE", "/apps/"+appName, nil)
+	if err != nil {
+		return err
+	}
+	httpResponse, err := tsuruCtx.RawHTTPClient().Do(request)
+	if err != nil {
+		return err
+	}
+	defer httpResponse.Body.Close()
+	if httpResponse.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("app %q not found", appName)
+	}
+	if httpResponse.StatusCode >= http.StatusBadRequest {
+		respBody, _ := io.ReadAll(httpResponse.Body)
+		return fmt.Errorf("failed to remove app %q: %s", appName, strings.TrimSpace(string(respBody)))
+	}
+	if _, err = streamOutput(tsuruCtx.Stdout, httpResponse.Body); err != nil {
+		return fmt.Errorf("failed to remove app %q: %w", appName, err)
+	}
+	return nil
+}
+
+// printAppRemoval shows what is destroyed removing the app.
+func printAppRemoval(out io.Writer, a *app) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "App %q is going to be removed, with:\n", a.Name)
+	renderUnitsSummary(&buf, a.Units, a.UnitsMetrics, a.Provisioner)
+	renderServiceInstanceBinds(&buf, a.ServiceInstanceBinds)
+	renderVolumeBinds(&buf, a.VolumeBinds)
+	out.Write(buf.Bytes())
+}
@@ -0,0 +1,121 @@
+// Copyright © 2023 tsuru-client authors
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tsuru/tsuru-client/v2/internal/tsuructx"
+)
+
+const appToRemove = `{"name":"myapp","provisioner":"kubernetes","units":[
+{"ID":"myapp-web-1","ProcessName":"web","Ready":true,"Routable":true},
+{"ID":"myapp-web-2","ProcessName":"web","Ready":true,"Routable":true},
+{"ID":"myapp-worker-1","ProcessName":"worker","Ready":false}],
+"serviceInstanceBinds":[{"service":"mysql","instance":"mydb","plan":"small"}],
+"volumeBinds":[{"ID":{"App":"myapp","MountPoint":"/data","Volume":"myvol"},"ReadOnly":false}]}`
+
+func removeMockServer(t *testing.T, removed *bool) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/1.0/apps/myapp", r.URL.Path)
+		switch r.Method {
+		case "GET":
+			fmt.Fprintln(w, appToRemove)
+		case "DELETE":
+			*removed = true
+			fmt.Fprintln(w, `{"Message":"removing app myapp\n"}`)
+		}
+	}))
+}
+
+func TestAppRemoveRun(t *testing.T) {
+	var removed bool
+	mockServer := removeMockServer(t, &removed)
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+	tsuruCtx.Stdin = &tsuructx.FakeStdin{Reader: strings.NewReader("myapp\n"), Terminal: true}
+
+	cmd := newAppRemoveCmd(tsuruCtx)
+	err := appRemoveCmdRun(tsuruCtx, cmd, []string{"myapp"})
+	assert.NoError(t, err)
+	assert.True(t, removed)
+	expected := `App "myapp" is going to be removed, with:
+Units: 3
++---------------------+-------+----------+---------------+------------+
+| Process             | Ready | Restarts | Avg CPU (abs) | Avg Memory |
++---------------------+-------+----------+---------------+------------+
+| web                 | 2/2   | 0        | 0%            | 0Mi        |
+| worker (unroutable) | 0/1   | 0        | 0%            | 0Mi        |
++---------------------+-------+----------+---------------+------------+
+
+Service instances: 1
++---------+-----------------+
+| Service | Instance (Plan) |
++---------+-----------------+
+| mysql   | mydb (small)    |
++---------+-----------------+
+
+Volumes: 1
++-------+------------+------+
+| Name  | MountPoint | Mode |
++-------+------------+------+
+| myvol | /data      | rw   |
++-------+------------+------+
+
+Type the name of the app to confirm: removing app myapp
+`
+	assert.Equal(t, expected, tsuruCtx.Stdout.(*strings.Builder).String())
+}
+
+func TestAppRemoveRunWrongName(t *testing.T) {
+	var removed bool
+	mockServer := removeMockServer(t, &removed)
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+	tsuruCtx.Stdin = &tsuructx.FakeStdin{Reader: strings.NewReader("y\n"), Terminal: true}
+
+	cmd := newAppRemoveCmd(tsuruCtx)
+	err := appRemoveCmdRun(tsuruCtx, cmd, []string{"myapp"})
+	assert.EqualError(t, err, `"y" doesn't match the app name, app "myapp" was not removed`)
+	assert.False(t, removed)
+}
+
+func TestAppRemoveRunWithoutTerminal(t *testing.T) {
+	var removed bool
+	mockServer := removeMockServer(t, &removed)
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+	tsuruCtx.Stdin = &tsuructx.FakeStdin{Reader: strings.NewReader("myapp\n")}
+
+	cmd := newAppRemoveCmd(tsuruCtx)
+	err := appRemoveCmdRun(tsuruCtx, cmd, []string{"myapp"})
+	assert.EqualError(t, err, `stdin is not a terminal to confirm the removal, use --yes to remove app "myapp" anyway`)
+	assert.False(t, removed)
+
+	cmd = newAppRemoveCmd(tsuruCtx)
+	cmd.Flags().Parse([]string{"-a", "myapp", "--yes"})
+	err = appRemoveCmdRun(tsuruCtx, cmd, []string{})
+	assert.NoError(t, err)
+	assert.True(t, removed)
+	assert.NotContains(t, tsuruCtx.Stdout.(*strings.Builder).String(), "Type the name")
+}
+
+func TestAppRemoveRunNotFound(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+
+	cmd := newAppRemoveCmd(tsuruCtx)
+	cmd.Flags().Parse([]string{"-y"})
+	err := appRemoveCmdRun(tsuruCtx, cmd, []string{"myapp"})
+	assert.EqualError(t, err, `app "myapp" not found`)
+}
@@ -0,0 +1,307 @@
+// Copyright © 2023 tsuru-client authors
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/tsuru/tsuru-client/v2/internal/tsuructx"
+	"github.com/tsuru/tsuru-client/v2/pkg/printer"
+)
+
+// runMarker tags the lines written by the wrapper of the command ran on the
+// units (see wrapRunCommand), so they can be told apart from other messages.
+const runMarker = "\x1e#tsuru-run#"
+
+var runPrefixColors = []string{"cyan", "green", "yellow", "magenta", "blue"}
+
+func newAppRunCmd(tsuruCtx *tsuructx.TsuruContext) *cobra.Command {
+	appRunCmd := &cobra.Command{
+		Use:   "run [APP] -- COMMAND [ARGS...]",
+		Short: "runs a command on the units of an app",
+		Long: `Runs a command on all the units of an app (non-interactively, stdin is not
+sent), or only on one of them:
+  --once       runs on a single unit
+  --unit       runs on the given unit (the unit ID may be shortened)
+  --isolated   runs on a new ephemeral unit, not affecting the running ones
+
+When the command runs on many units, each line of its output is prefixed with
+the ID of the unit that wrote it. The command fails when it fails on any unit,
+exiting with the same exit code.`,
+		Example: `$ tsuru app run myapp -- ls -la
+$ tsuru app run -a myapp --once -- python manage.py migrate
+$ tsuru app run myapp --unit 5f4d -- cat /etc/hosts`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return appRunCmdRun(tsuruCtx, cmd, args)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return completeAppNames(tsuruCtx, cmd, args, toComplete)
+		},
+		Args: cobra.MinimumNArgs(1),
+	}
+
+	appRunCmd.Flags().StringP("app", "a", "", "The name of the app (may be passed as argument)")
+	appRunCmd.Flags().BoolP("once", "o", false, "Run the command on a single unit")
+	appRunCmd.Flags().BoolP("isolated", "i", false, "Run the command on a new ephemeral unit")
+	appRunCmd.Flags().StringP("unit", "u", "", "Run the command on the given unit")
+	return appRunCmd
+}
+
+func appRunCmdRun(tsuruCtx *tsuructx.TsuruContext, cmd *cobra.Command, args []string) error {
+	appName := cmd.Flag("app").Value.String()
+	command := args
+	if dash := cmd.ArgsLenAtDash(); dash >= 0 {
+		if appName == "" && dash > 0 {
+			appName = args[0]
+		}
+		if dash > 1 || (dash == 1 && cmd.Flag("app").Value.String() != "") {
+			return fmt.Errorf("too many arguments before --")
+		}
+		command = args[dash:]
+	} else if appName == "" {
+		appName, command = args[0], args[1:]
+	}
+	if appName == "" {
+		return fmt.Errorf("no app was provided. Please provide an app name or use the --app flag")
+	}
+	if len(command) == 0 {
+		return fmt.Errorf("no command was provided")
+	}
+	once, _ := cmd.Flags().GetBool("once")
+	isolated, _ := cmd.Flags().GetBool("isolated")
+	unitID := cmd.Flag("unit").Value.String()
+	if unitID != "" && (once || isolated) {
+		return fmt.Errorf("--unit can't be used with --once or --isolated")
+	}
+	cmd.SilenceUsage = true
+
+	manyUnits := false
+	if !once && !isolated {
+		a, err := getApp(tsuruCtx, appName)
+		if err != nil {
+			return err
+		}
+		if unitID != "" {
+			if unitID, err = findUnit(a, unitID); err != nil {
+				return err
+			}
+		}
+		manyUnits = unitID == "" && len(a.Units) > 1
+	}
+
+	values := url.Values{}
+	values.Set("command", wrapRunCommand(strings.Join(command, " "), unitID))
+	values.Set("once", strconv.FormatBool(once))
+	values.Set("isolated", strconv.FormatBool(isolated))
+	request, err := tsuruCtx.NewRequest("POST", "/apps/"+appName+"/run", strings.NewReader(values.Encode()))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	httpResponse, err := tsuruCtx.RawHTTPClient().Do(request)
+	if err != nil {
+		return err
+	}
+	defer httpResponse.Body.Close()
+	if httpResponse.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("app %q not found", appName)
+	}
+	if httpResponse.StatusCode >= http.StatusBadRequest {
+		respBody, _ := io.ReadAll(httpResponse.Body)
+		return fmt.Errorf("failed to run the command on app %q: %s", appName, strings.TrimSpace(string(respBody)))
+	}
+
+	out := &runOutput{
+		out:       tsuruCtx.Stdout,
+		unitID:    unitID,
+		prefixed:  manyUnits,
+		colorify:  printer.Colorify{DisableColors: tsuruCtx.Viper.IsSet("disable-colors")},
+		exitCodes: map[string]int{},
+	}
+	_, err = streamOutput(out, httpResponse.Body)
+	out.Flush()
+	if err != nil {
+		return fmt.Errorf("failed to run the command on app %q: %w", appName, err)
+	}
+	return out.Err()
+}
+
+// findUnit returns the ID of the unit of the app with the given ID, which may
+// be shortened to its beginning.
+func findUnit(a *app, unitID string) (string, error) {
+	var found []string
+	for _, u := range a.Units {
+		if u.ID == unitID {
+			return u.ID, nil
+		}
+		if strings.HasPrefix(u.ID, unitID) {
+			found = append(found, u.ID)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return "", fmt.Errorf("unit %q not found on app %q", unitID, a.Name)
+	case 1:
+		return found[0], nil
+	}
+	return "", fmt.Errorf("unit %q is ambiguous on app %q, it may be any of: %s", unitID, a.Name, strings.Join(found, ", "))
+}
+
+// wrapRunCommand returns a shell script running command that writes each line
+// of its output (stdout and stderr) as "<marker><unit> <line>", ending with
+// "<marker><unit> exit:<code>". With unitID, command only runs on that unit.
+func wrapRunCommand(command, unitID string) string {
+	var script strings.Builder
+	script.WriteString(`u=${HOSTNAME:-$(hostname)}; `)
+	if unitID != "" {
+		fmt.Fprintf(&script, `[ "$u" = %s ] || exit 0; `, shellQuote(unitID))
+	}
+	fmt.Fprintf(&script, `{ (%s
+) 2>&1; printf '\n%%sexit:%%d\n' %s "$?"; } | while IFS= read -r l || [ -n "$l" ]; do printf '%%s%%s %%s\n' %s "$u" "$l"; done`,
+		command, shellQuote(runMarker), shellQuote(runMarker))
+	return script.String()
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// runOutput writes the output of the command ran on the units, parsing the
+// lines written by the wrapper of the command (see wrapRunCommand).
+type runOutput struct {
+	out      io.Writer
+	unitID   string // the unit given by --unit, if any
+	prefixed bool
+	colorify printer.Colorify
+	buf      []byte
+
+	exitCodes map[string]int
+	units     []string        // in the order they were seen, for the colors
+	pending   map[string]bool // units whose last (empty) line is held back
+}
+
+func (w *runOutput) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		w.writeLine(string(w.buf[:i]))
+		w.buf = w.buf[i+1:]
+	}
+}
+
+// Flush writes the last line, when it doesn't end with a new line.
+func (w *runOutput) Flush() {
+	if len(w.buf) > 0 {
+		w.writeLine(string(w.buf))
+		w.buf = nil
+	}
+}
+
+func (w *runOutput) writeLine(line string) {
+	rest, ok := strings.CutPrefix(line, runMarker)
+	if !ok {
+		fmt.Fprintln(w.out, line)
+		return
+	}
+	unitID, text, _ := strings.Cut(rest, " ")
+	if _, seen := w.exitCodes[unitID]; !seen {
+		w.exitCodes[unitID] = -1
+		w.units = append(w.units, unitID)
+	}
+	if code, isExit := strings.CutPrefix(text, runMarker+"exit:"); isExit {
+		// the empty line held back was written by the wrapper, so the exit
+		// code starts on its own line even when the output doesn't end with
+		// a new line
+		w.exitCodes[unitID], _ = strconv.Atoi(code)
+		delete(w.pending, unitID)
+		return
+	}
+	if w.pending[unitID] {
+		w.print(unitID, "")
+	}
+	if text == "" {
+		if w.pending == nil {
+			w.pending = map[string]bool{}
+		}
+		w.pending[unitID] = true
+		return
+	}
+	delete(w.pending, unitID)
+	w.print(unitID, text)
+}
+
+func (w *runOutput) print(unitID, text string) {
+	if !w.prefixed {
+		fmt.Fprintln(w.out, text)
+		return
+	}
+	color := runPrefixColors[0]
+	for i, id := range w.units {
+		if id == unitID {
+			color = runPrefixColors[i%len(runPrefixColors)]
+		}
+	}
+	fmt.Fprintf(w.out, "%s %s\n", w.colorify.Colorfy("["+unitID+"]", color, "", ""), text)
+}
+
+// Err returns the error of the units where the command failed, if any. As
+// the wrapper always writes an exit code, it's an error too when none came
+// back, or none from the unit given by --unit (eg: its hostname didn't match).
+func (w *runOutput) Err() error {
+	if len(w.exitCodes) == 0 {
+		return fmt.Errorf("the command didn't run on any unit, no exit code came back")
+	}
+	if _, ran := w.exitCodes[w.unitID]; w.unitID != "" && !ran {
+		return fmt.Errorf("the command didn't run on unit %q, no exit code came back from it", w.unitID)
+	}
+	var failed []string
+	for unitID, code := range w.exitCodes {
+		if code != 0 {
+			failed = append(failed, unitID)
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	sort.Strings(failed)
+	return &runFailedError{exitCodes: w.exitCodes, failed: failed}
+}
+
+// runFailedError is returned when the command failed on some unit. The exit
+// code of the client is the one of the command on the first failed unit.
+type runFailedError struct {
+	exitCodes map[string]int
+	failed    []string
+}
+
+func (e *runFailedError) Error() string {
+	var units []string
+	for _, unitID := range e.failed {
+		if code := e.exitCodes[unitID]; code > 0 {
+			units = append(units, fmt.Sprintf("%s (exit code %d)", unitID, code))
+		} else {
+			units = append(units, unitID+" (no exit code)")
+		}
+	}
+	return fmt.Sprintf("the command failed on %d unit(s): %s", len(units), strings.Join(units, ", "))
+}
+
+func (e *runFailedError) ExitCode() int {
+	if code := e.exitCodes[e.failed[0]]; code > 0 {
+		return code
+	}
+	return 1
+}
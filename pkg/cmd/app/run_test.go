@@ -0,0 +1,175 @@
+// Copyright © 2023 tsuru-client authors
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tsuru/tsuru-client/v2/internal/tsuructx"
+)
+
+const appToRun = `{"name":"myapp","units":[{"ID":"myapp-web-5f4d"},{"ID":"myapp-web-9a1b"}]}`
+
+// runOutputMessages returns the JSON messages streamed by the server for the
+// given lines, written by the units as the wrapper of the command does.
+func runOutputMessages(lines ...string) string {
+	var out strings.Builder
+	for _, line := range lines {
+		unitID, text, _ := strings.Cut(line, " ")
+		msg, _ := json.Marshal(jsonMessage{Message: runMarker + unitID + " " + text + "\n"})
+		fmt.Fprintln(&out, string(msg))
+	}
+	return out.String()
+}
+
+func runMockServer(t *testing.T, form *url.Values, output string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			assert.Equal(t, "/1.0/apps/myapp", r.URL.Path)
+			fmt.Fprintln(w, appToRun)
+			return
+		}
+		assert.Equal(t, "/1.0/apps/myapp/run", r.URL.Path)
+		assert.NoError(t, r.ParseForm())
+		*form = r.PostForm
+		fmt.Fprint(w, output)
+	}))
+}
+
+func TestAppRunRunManyUnits(t *testing.T) {
+	var form url.Values
+	mockServer := runMockServer(t, &form, runOutputMessages(
+		"myapp-web-5f4d hello",
+		"myapp-web-9a1b hello",
+		"myapp-web-5f4d ",
+		"myapp-web-5f4d "+runMarker+"exit:0",
+		"myapp-web-9a1b oops",
+		"myapp-web-9a1b ",
+		"myapp-web-9a1b ",
+		"myapp-web-9a1b "+runMarker+"exit:2",
+	))
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+	tsuruCtx.Viper.Set("disable-colors", true)
+
+	cmd := newAppRunCmd(tsuruCtx)
+	cmd.SetArgs([]string{"myapp", "--", "ls", "-la"})
+	cmd.SilenceErrors = true
+	err := cmd.Execute()
+	assert.EqualError(t, err, "the command failed on 1 unit(s): myapp-web-9a1b (exit code 2)")
+	var exitErr interface{ ExitCode() int }
+	assert.True(t, errors.As(err, &exitErr))
+	assert.Equal(t, 2, exitErr.ExitCode())
+
+	assert.Equal(t, "false", form.Get("once"))
+	assert.Equal(t, "false", form.Get("isolated"))
+	assert.Contains(t, form.Get("command"), "{ (ls -la\n) 2>&1;")
+	expected := `[myapp-web-5f4d] hello
+[myapp-web-9a1b] hello
+[myapp-web-9a1b] oops
+[myapp-web-9a1b] 
+`
+	assert.Equal(t, expected, tsuruCtx.Stdout.(*strings.Builder).String())
+}
+
+func TestAppRunRunOnce(t *testing.T) {
+	var form url.Values
+	mockServer := runMockServer(t, &form, runOutputMessages(
+		"myapp-web-5f4d migrated",
+		"myapp-web-5f4d ",
+		"myapp-web-5f4d "+runMarker+"exit:0",
+	))
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+
+	cmd := newAppRunCmd(tsuruCtx)
+	cmd.SetArgs([]string{"-a", "myapp", "--once", "--", "./manage.py", "migrate"})
+	err := cmd.Execute()
+	assert.NoError(t, err)
+	assert.Equal(t, "true", form.Get("once"))
+	assert.Equal(t, "migrated\n", tsuruCtx.Stdout.(*strings.Builder).String())
+}
+
+func TestAppRunRunUnit(t *testing.T) {
+	var form url.Values
+	mockServer := runMockServer(t, &form, runOutputMessages("myapp-web-9a1b "+runMarker+"exit:0"))
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+
+	cmd := newAppRunCmd(tsuruCtx)
+	cmd.SetArgs([]string{"myapp", "--unit", "myapp-web-9", "--", "true"})
+	err := cmd.Execute()
+	assert.NoError(t, err)
+	assert.Contains(t, form.Get("command"), `[ "$u" = 'myapp-web-9a1b' ] || exit 0;`)
+
+	cmd = newAppRunCmd(tsuruCtx)
+	cmd.SetArgs([]string{"myapp", "--unit", "myapp-web", "--", "true"})
+	cmd.SilenceErrors = true
+	err = cmd.Execute()
+	assert.EqualError(t, err, `unit "myapp-web" is ambiguous on app "myapp", it may be any of: myapp-web-5f4d, myapp-web-9a1b`)
+}
+
+func TestAppRunRunNoExitCodes(t *testing.T) {
+	var form url.Values
+	mockServer := runMockServer(t, &form, runOutputMessages("myapp-web-5f4d "+runMarker+"exit:0"))
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+
+	cmd := newAppRunCmd(tsuruCtx)
+	cmd.SetArgs([]string{"myapp", "--unit", "myapp-web-9a1b", "--", "true"})
+	cmd.SilenceErrors = true
+	err := cmd.Execute()
+	assert.EqualError(t, err, `the command didn't run on unit "myapp-web-9a1b", no exit code came back from it`)
+
+	mockServer = runMockServer(t, &form, `{"Message":"some output\n"}`+"\n")
+	tsuruCtx = tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+	cmd = newAppRunCmd(tsuruCtx)
+	cmd.SetArgs([]string{"myapp", "--", "true"})
+	cmd.SilenceErrors = true
+	err = cmd.Execute()
+	assert.EqualError(t, err, "the command didn't run on any unit, no exit code came back")
+	assert.Equal(t, "some output\n", tsuruCtx.Stdout.(*strings.Builder).String())
+}
+
+func TestAppRunRunServerError(t *testing.T) {
+	var form url.Values
+	mockServer := runMockServer(t, &form, `{"Message":"","Error":"App must be available to run non-isolated commands"}`+"\n")
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+
+	cmd := newAppRunCmd(tsuruCtx)
+	cmd.SetArgs([]string{"myapp", "--isolated", "--", "true"})
+	cmd.SilenceErrors = true
+	err := cmd.Execute()
+	assert.EqualError(t, err, `failed to run the command on app "myapp": App must be available to run non-isolated commands`)
+}
+
+func TestAppRunRunArgs(t *testing.T) {
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	for _, tt := range []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"myapp"}, "no command was provided"},
+		{[]string{"myapp", "other", "--", "ls"}, "too many arguments before --"},
+		{[]string{"-a", "myapp", "other", "--", "ls"}, "too many arguments before --"},
+		{[]string{"myapp", "--once", "--unit", "u1", "--", "ls"}, "--unit can't be used with --once or --isolated"},
+	} {
+		cmd := newAppRunCmd(tsuruCtx)
+		cmd.SetArgs(tt.args)
+		cmd.SilenceErrors = true
+		cmd.SilenceUsage = true
+		assert.EqualError(t, cmd.Execute(), tt.err, tt.args)
+	}
+}
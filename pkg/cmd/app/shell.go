@@ -193,6 +193,25 @@ func getStdinSize(in tsuructx.DescriptorReader) (width, height int) {
 	return
 }
 
+func isTerminal(in tsuructx.DescriptorReader) bool {
+	if in == nil || reflect.ValueOf(in).IsNil() {
+		return false
+	}
+	if t, ok := in.(interface{ IsTerminal() bool }); ok { // eg: tsuructx.FakeStdin
+		return t.IsTerminal()
+	}
+	return term.IsTerminal(int(in.Fd()))
+}
+
+// isTerminalWriter reports whether out writes to a terminal.
+func isTerminalWriter(out io.Writer) bool {
+	if t, ok := out.(interface{ IsTerminal() bool }); ok {
+		return t.IsTerminal()
+	}
+	f, ok := out.(interface{ Fd() uintptr })
+	return ok && term.IsTerminal(int(f.Fd()))
+}
+
 func setupRawStdin(in tsuructx.DescriptorReader) (restoreStdin func(), err error) {
 	restoreStdin = func() {}
 	if in == nil || reflect.ValueOf(in).IsNil() {
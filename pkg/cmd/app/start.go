@@ -0,0 +1,143 @@
+// Copyright © 2023 tsuru-client authors
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tsuru/tsuru-client/v2/internal/tsuructx"
+)
+
+func newAppStartCmd(tsuruCtx *tsuructx.TsuruContext) *cobra.Command {
+	return newAppLifecycleCmd(tsuruCtx, "start", &cobra.Command{
+		Use:   "start [APP]",
+		Short: "starts an app, or one of its processes",
+		Long: `Starts an app, or one of its processes or versions.
+
+With [[--wait]], the units of what was started are checked until all of them
+are ready, failing when [[--timeout]] expires first.`,
+		Example: `$ tsuru app start myapp
+$ tsuru app start -a myapp --process worker --version 2 --wait`,
+	})
+}
+
+func newAppStopCmd(tsuruCtx *tsuructx.TsuruContext) *cobra.Command {
+	return newAppLifecycleCmd(tsuruCtx, "stop", &cobra.Command{
+		Use:   "stop [APP]",
+		Short: "stops an app, or one of its processes",
+		Long: `Stops an app, or one of its processes or versions.
+
+With [[--wait]], the units of what was stopped are checked until all of them
+are gone, failing when [[--timeout]] expires first.`,
+		Example: `$ tsuru app stop myapp
+$ tsuru app stop -a myapp --process worker --wait`,
+	})
+}
+
+func newAppRestartCmd(tsuruCtx *tsuructx.TsuruContext) *cobra.Command {
+	return newAppLifecycleCmd(tsuruCtx, "restart", &cobra.Command{
+		Use:   "restart [APP]",
+		Short: "restarts an app, or one of its processes",
+		Long: `Restarts an app, or one of its processes or versions.
+
+With [[--wait]], the units of what was restarted are checked until all of them
+are ready, failing when [[--timeout]] expires first.`,
+		Example: `$ tsuru app restart myapp
+$ tsuru app restart -a myapp --process web --wait --timeout 10m`,
+	})
+}
+
+// newAppLifecycleCmd completes cmd as the command running the given action
+// (start, stop or restart) on an app.
+func newAppLifecycleCmd(tsuruCtx *tsuructx.TsuruContext, action string, cmd *cobra.Command) *cobra.Command {
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		return appLifecycleCmdRun(tsuruCtx, action, cmd, args)
+	}
+	cmd.ValidArgsFunction = func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return completeAppNames(tsuruCtx, cmd, args, toComplete)
+	}
+	cmd.Args = cobra.RangeArgs(0, 1)
+
+	cmd.Flags().StringP("app", "a", "", "The name of the app (may be passed as argument)")
+	cmd.Flags().StringP("process", "p", "", "The name of the process to "+action+" (default: all of them)")
+	cmd.Flags().String("version", "", "The version to "+action+" (default: all of them)")
+	cmd.Flags().Bool("wait", false, "Wait for the units to be "+lifecycleWaitState(action))
+	cmd.Flags().Duration("timeout", 5*time.Minute, "The maximum time to wait for the units (with --wait)")
+	return cmd
+}
+
+func appLifecycleCmdRun(tsuruCtx *tsuructx.TsuruContext, action string, cmd *cobra.Command, args []string) error {
+	appName, err := appNameFromArgsOrFlag(cmd, args)
+	if err != nil {
+		return err
+	}
+	cmd.SilenceUsage = true
+
+	filter := unitFilter{
+		process: cmd.Flag("process").Value.String(),
+		version: cmd.Flag("version").Value.String(),
+	}
+	if err = appLifecycleAction(tsuruCtx, action, appName, filter); err != nil {
+		return err
+	}
+
+	if wait, _ := cmd.Flags().GetBool("wait"); !wait {
+		return nil
+	}
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	state, done := lifecycleWaitState(action), allUnitsReady
+	if action == "stop" {
+		done = allUnitsStopped
+	}
+	fmt.Fprintf(tsuruCtx.Stdout, "Waiting for the units of %s of app %q to be %s...\n", filter, appName, state)
+	if err = waitForUnits(tsuruCtx, appName, filter, timeout, unitsCondition(done), nil); err != nil {
+		return err
+	}
+	fmt.Fprintf(tsuruCtx.Stdout, "The units of %s of app %q are %s.\n", filter, appName, state)
+	return nil
+}
+
+// appLifecycleAction starts, stops or restarts the units of the app matching
+// filter, writing the output of tsuru.
+func appLifecycleAction(tsuruCtx *tsuructx.TsuruContext, action, appName string, filter unitFilter) error {
+	values := url.Values{}
+	values.Set("process", filter.process)
+	values.Set("version", filter.version)
+	request, err := tsuruCtx.NewRequest("POST", "/apps/"+appName+"/"+action, strings.NewReader(values.Encode()))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	httpResponse, err := tsuruCtx.RawHTTPClient().Do(request)
+	if err != nil {
+		return err
+	}
+	defer httpResponse.Body.Close()
+	if httpResponse.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("app %q not found", appName)
+	}
+	if httpResponse.StatusCode >= http.StatusBadRequest {
+		respBody, _ := io.ReadAll(httpResponse.Body)
+		return fmt.Errorf("failed to %s app %q: %s", action, appName, strings.TrimSpace(string(respBody)))
+	}
+	if _, err = streamOutput(tsuruCtx.Stdout, httpResponse.Body); err != nil {
+		return fmt.Errorf("failed to %s app %q: %w", action, appName, err)
+	}
+	return nil
+}
+
+// lifecycleWaitState returns the state of the units waited for after action.
+func lifecycleWaitState(action string) string {
+	if action == "stop" {
+		return "stopped"
+	}
+	return "ready"
+}
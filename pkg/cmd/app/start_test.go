@@ -0,0 +1,129 @@
+// Copyright © 2023 tsuru-client authors
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tsuru/tsuru-client/v2/internal/tsuructx"
+)
+
+func fastWaitPoll(t *testing.T) {
+	old := waitPollInterval
+	waitPollInterval = time.Millisecond
+	t.Cleanup(func() { waitPollInterval = old })
+}
+
+func TestAppStartRun(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "POST", r.Method)
+		assert.Equal(t, "/1.0/apps/myapp/start", r.URL.Path)
+		assert.Equal(t, "application/x-www-form-urlencoded", r.Header.Get("Content-Type"))
+		assert.Equal(t, "worker", r.FormValue("process"))
+		assert.Equal(t, "2", r.FormValue("version"))
+		fmt.Fprintln(w, `{"Message":"---- Starting the process \"worker\" ----\n"}`)
+		fmt.Fprintln(w, `{"Message":" ---> Started unit myapp-worker-1\n"}`)
+	}))
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+
+	cmd := newAppStartCmd(tsuruCtx)
+	cmd.Flags().Parse([]string{"-a", "myapp", "-p", "worker", "--version", "2"})
+	err := cmd.RunE(cmd, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "---- Starting the process \"worker\" ----\n ---> Started unit myapp-worker-1\n", tsuruCtx.Stdout.(*strings.Builder).String())
+}
+
+func TestAppRestartRunWait(t *testing.T) {
+	fastWaitPoll(t)
+	polls := 0
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			assert.Equal(t, "/1.0/apps/myapp/restart", r.URL.Path)
+			fmt.Fprintln(w, `{"Message":"restarting\n"}`)
+			return
+		}
+		assert.Equal(t, "/1.0/apps/myapp", r.URL.Path)
+		polls++
+		ready := polls > 2
+		fmt.Fprintf(w, `{"name":"myapp","units":[{"ID":"web-1","ProcessName":"web","Ready":%v},{"ID":"worker-1","ProcessName":"worker","Ready":false}]}`, ready)
+	}))
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+
+	cmd := newAppRestartCmd(tsuruCtx)
+	cmd.Flags().Parse([]string{"--process", "web", "--wait"})
+	err := cmd.RunE(cmd, []string{"myapp"})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, polls)
+	expected := `restarting
+Waiting for the units of process web of app "myapp" to be ready...
+The units of process web of app "myapp" are ready.
+`
+	assert.Equal(t, expected, tsuruCtx.Stdout.(*strings.Builder).String())
+}
+
+func TestAppStartRunWaitStartedUnitsAreNotReady(t *testing.T) {
+	fastWaitPoll(t)
+	polls := 0
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			return
+		}
+		polls++
+		if polls < 3 {
+			fmt.Fprintln(w, `{"name":"myapp","units":[{"ID":"web-1","ProcessName":"web","Status":"started"}]}`)
+			return
+		}
+		fmt.Fprintln(w, `{"name":"myapp","units":[{"ID":"web-1","ProcessName":"web","Status":"started","Ready":true}]}`)
+	}))
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+
+	cmd := newAppStartCmd(tsuruCtx)
+	cmd.Flags().Parse([]string{"--wait"})
+	err := cmd.RunE(cmd, []string{"myapp"})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, polls)
+}
+
+func TestAppStopRunWaitTimeout(t *testing.T) {
+	fastWaitPoll(t)
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			assert.Equal(t, "/1.0/apps/myapp/stop", r.URL.Path)
+			return
+		}
+		fmt.Fprintln(w, `{"name":"myapp","units":[{"ID":"web-1","ProcessName":"web","Status":"started"}]}`)
+	}))
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+
+	cmd := newAppStopCmd(tsuruCtx)
+	cmd.Flags().Parse([]string{"--wait", "--timeout", "10ms"})
+	err := cmd.RunE(cmd, []string{"myapp"})
+	assert.EqualError(t, err, `timeout after 10ms waiting for the units of app "myapp"`)
+	assert.Equal(t, "Waiting for the units of all processes of app \"myapp\" to be stopped...\n", tsuruCtx.Stdout.(*strings.Builder).String())
+}
+
+func TestAppStartRunError(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"Message":"starting\n"}`)
+		fmt.Fprintln(w, `{"Message":"","Error":"unit failed to start"}`)
+	}))
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+
+	cmd := newAppStartCmd(tsuruCtx)
+	cmd.Flags().Parse([]string{"--wait"})
+	err := cmd.RunE(cmd, []string{"myapp"})
+	assert.EqualError(t, err, `failed to start app "myapp": unit failed to start`)
+}
@@ -0,0 +1,339 @@
+// Copyright © 2023 tsuru-client authors
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/tsuru/tsuru-client/v2/internal/parser"
+	"github.com/tsuru/tsuru-client/v2/internal/tsuructx"
+)
+
+func newAppUnitCmd(tsuruCtx *tsuructx.TsuruContext) *cobra.Command {
+	appUnitCmd := &cobra.Command{
+		Use:   "unit",
+		Short: "manages the units of apps",
+		Long: `Manages the units of apps: adds and removes units of a process, sets how many
+units a process has, and kills units.`,
+		Args: cobra.NoArgs,
+	}
+
+	appUnitCmd.AddCommand(newAppUnitAddCmd(tsuruCtx))
+	appUnitCmd.AddCommand(newAppUnitRemoveCmd(tsuruCtx))
+	appUnitCmd.AddCommand(newAppUnitSetCmd(tsuruCtx))
+	appUnitCmd.AddCommand(newAppUnitKillCmd(tsuruCtx))
+	return appUnitCmd
+}
+
+func newAppUnitAddCmd(tsuruCtx *tsuructx.TsuruContext) *cobra.Command {
+	appUnitAddCmd := &cobra.Command{
+		Use:   "add [APP] N",
+		Short: "adds units to an app",
+		Long: `Adds N units to a process of an app. The process may be omitted when the app
+has a single one.`,
+		Example: `$ tsuru app unit add myapp 2
+$ tsuru app unit add -a myapp 1 --process worker --version 3`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return appUnitChangeCmdRun(tsuruCtx, "add", cmd, args)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return completeAppNames(tsuruCtx, cmd, args, toComplete)
+		},
+		Args: cobra.RangeArgs(1, 2),
+	}
+	addUnitFilterFlags(appUnitAddCmd, "add units to")
+	return appUnitAddCmd
+}
+
+func newAppUnitRemoveCmd(tsuruCtx *tsuructx.TsuruContext) *cobra.Command {
+	appUnitRemoveCmd := &cobra.Command{
+		Use:   "remove [APP] N",
+		Short: "removes units from an app",
+		Long: `Removes N units from a process of an app. The process may be omitted when the
+app has a single one.`,
+		Example: `$ tsuru app unit remove myapp 2
+$ tsuru app unit remove -a myapp 1 --process worker`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return appUnitChangeCmdRun(tsuruCtx, "remove", cmd, args)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return completeAppNames(tsuruCtx, cmd, args, toComplete)
+		},
+		Args: cobra.RangeArgs(1, 2),
+	}
+	addUnitFilterFlags(appUnitRemoveCmd, "remove units from")
+	return appUnitRemoveCmd
+}
+
+func newAppUnitSetCmd(tsuruCtx *tsuructx.TsuruContext) *cobra.Command {
+	appUnitSetCmd := &cobra.Command{
+		Use:   "set [APP] N",
+		Short: "sets the number of units of an app",
+		Long: `Sets the number of units of a process of an app to N, adding or removing the
+units needed. Nothing is done when the process already has N units, so the
+command may be run again safely (eg: by scripts).
+
+The [[--process]] and [[--version]] parameters may be omitted when the app has
+a single process and version.`,
+		Example: `$ tsuru app unit set myapp 3
+$ tsuru app unit set -a myapp 5 --process worker --version 2`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return appUnitSetCmdRun(tsuruCtx, cmd, args)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return completeAppNames(tsuruCtx, cmd, args, toComplete)
+		},
+		Args: cobra.RangeArgs(1, 2),
+	}
+	addUnitFilterFlags(appUnitSetCmd, "set the units of")
+	return appUnitSetCmd
+}
+
+func newAppUnitKillCmd(tsuruCtx *tsuructx.TsuruContext) *cobra.Command {
+	appUnitKillCmd := &cobra.Command{
+		Use:   "kill [APP] UNIT",
+		Short: "kills a unit of an app",
+		Long: `Kills a unit of an app, which is replaced by a new one. The unit may be given
+by its ID, or by the short ID shown by app info (other prefixes of the ID are
+not accepted, so a mistyped ID never kills another unit). With
+[[--process]] and [[--version]], only the units of that process and version
+are looked up.
+
+With [[--force]], the unit is killed right away, without waiting for it to
+finish gracefully.`,
+		Example: `$ tsuru app unit kill myapp myapp-web-6d8f7c9b5-x2x9z
+$ tsuru app unit kill -a myapp 8f2c31a4b6e0 --force`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return appUnitKillCmdRun(tsuruCtx, cmd, args)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return completeAppNames(tsuruCtx, cmd, args, toComplete)
+		},
+		Args: cobra.RangeArgs(1, 2),
+	}
+	addUnitFilterFlags(appUnitKillCmd, "kill a unit of")
+	appUnitKillCmd.Flags().BoolP("force", "f", false, "Kill the unit without waiting for it to finish")
+	return appUnitKillCmd
+}
+
+func addUnitFilterFlags(cmd *cobra.Command, action string) {
+	cmd.Flags().StringP("app", "a", "", "The name of the app (may be passed as argument)")
+	cmd.Flags().StringP("process", "p", "", "The name of the process to "+action)
+	cmd.Flags().String("version", "", "The version to "+action)
+}
+
+// unitCmdArgs returns the app, the single argument after it and the process
+// and version given to the unit commands.
+func unitCmdArgs(cmd *cobra.Command, args []string) (string, string, unitFilter, error) {
+	appName, args, err := appNameAndArgs(cmd, args)
+	if err != nil {
+		return "", "", unitFilter{}, err
+	}
+	if len(args) != 1 {
+		return "", "", unitFilter{}, fmt.Errorf("expected 1 argument after the app name, got %d", len(args))
+	}
+	filter := unitFilter{
+		process: cmd.Flag("process").Value.String(),
+		version: cmd.Flag("version").Value.String(),
+	}
+	return appName, args[0], filter, nil
+}
+
+func parseUnitsCount(arg string, allowZero bool) (int, error) {
+	n, err := strconv.Atoi(arg)
+	if err != nil || n < 0 || (n == 0 && !allowZero) {
+		minimum := "greater than 0"
+		if allowZero {
+			minimum = "0 or greater"
+		}
+		return 0, fmt.Errorf("invalid number of units %q, it must be an integer %s", arg, minimum)
+	}
+	return n, nil
+}
+
+func appUnitChangeCmdRun(tsuruCtx *tsuructx.TsuruContext, action string, cmd *cobra.Command, args []string) error {
+	appName, arg, filter, err := unitCmdArgs(cmd, args)
+	if err != nil {
+		return err
+	}
+	n, err := parseUnitsCount(arg, false)
+	if err != nil {
+		return err
+	}
+	cmd.SilenceUsage = true
+	return changeAppUnits(tsuruCtx, action, appName, n, filter)
+}
+
+func appUnitSetCmdRun(tsuruCtx *tsuructx.TsuruContext, cmd *cobra.Command, args []string) error {
+	appName, arg, filter, err := unitCmdArgs(cmd, args)
+	if err != nil {
+		return err
+	}
+	desired, err := parseUnitsCount(arg, true)
+	if err != nil {
+		return err
+	}
+	cmd.SilenceUsage = true
+
+	a, err := getApp(tsuruCtx, appName)
+	if err != nil {
+		return err
+	}
+	if filter.process == "" {
+		processes := unitKeys(a.Units, func(u unit) string { return u.ProcessName })
+		if len(processes) > 1 {
+			return fmt.Errorf("app %q has many processes (%s), use --process to choose one", appName, strings.Join(processes, ", "))
+		}
+		if len(processes) == 1 {
+			filter.process = processes[0]
+		}
+	}
+	if filter.version == "" {
+		var units []unit
+		for _, u := range a.Units {
+			if filter.match(u) {
+				units = append(units, u)
+			}
+		}
+		versions := unitKeys(units, func(u unit) string { return strconv.Itoa(u.Version) })
+		if len(versions) > 1 {
+			return fmt.Errorf("app %q has many versions (%s), use --version to choose one", appName, strings.Join(versions, ", "))
+		}
+		if len(versions) == 1 {
+			filter.version = versions[0]
+		}
+	}
+	current := 0
+	for _, u := range a.Units {
+		if filter.match(u) {
+			current++
+		}
+	}
+
+	switch {
+	case current == desired:
+		fmt.Fprintf(tsuruCtx.Stdout, "App %q already has %d units (%s).\n", appName, desired, filter)
+		return nil
+	case current < desired:
+		fmt.Fprintf(tsuruCtx.Stdout, "Adding %d units to app %q (%s: %d -> %d).\n", desired-current, appName, filter, current, desired)
+		return changeAppUnits(tsuruCtx, "add", appName, desired-current, filter)
+	}
+	fmt.Fprintf(tsuruCtx.Stdout, "Removing %d units from app %q (%s: %d -> %d).\n", current-desired, appName, filter, current, desired)
+	return changeAppUnits(tsuruCtx, "remove", appName, current-desired, filter)
+}
+
+// unitKeys returns the distinct keys of the units, sorted.
+func unitKeys(units []unit, key func(unit) string) []string {
+	seen := map[string]bool{}
+	var keys []string
+	for _, u := range units {
+		if k := key(u); !seen[k] {
+			seen[k] = true
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// changeAppUnits adds or removes n units of the process and version of the
+// app given by filter, writing the output of tsuru.
+func changeAppUnits(tsuruCtx *tsuructx.TsuruContext, action, appName string, n int, filter unitFilter) error {
+	values := url.Values{}
+	values.Set("units", strconv.Itoa(n))
+	values.Set("process", filter.process)
+	values.Set("version", filter.version)
+	method, path, body := "PUT", "/apps/"+appName+"/units", values.Encode()
+	if action == "remove" {
+		method, path, body = "DELETE", path+"?"+body, ""
+	}
+	request, err := tsuruCtx.NewRequest(method, path, strings.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	httpResponse, err := tsuruCtx.RawHTTPClient().Do(request)
+	if err != nil {
+		return err
+	}
+	defer httpResponse.Body.Close()
+	direction := "to"
+	if action == "remove" {
+		direction = "from"
+	}
+	if httpResponse.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("app %q not found", appName)
+	}
+	if httpResponse.StatusCode >= http.StatusBadRequest {
+		respBody, _ := io.ReadAll(httpResponse.Body)
+		return fmt.Errorf("failed to %s units %s app %q: %s", action, direction, appName, strings.TrimSpace(string(respBody)))
+	}
+	if _, err = streamOutput(tsuruCtx.Stdout, httpResponse.Body); err != nil {
+		return fmt.Errorf("failed to %s units %s app %q: %w", action, direction, appName, err)
+	}
+	return nil
+}
+
+func appUnitKillCmdRun(tsuruCtx *tsuructx.TsuruContext, cmd *cobra.Command, args []string) error {
+	appName, unitID, filter, err := unitCmdArgs(cmd, args)
+	if err != nil {
+		return err
+	}
+	cmd.SilenceUsage = true
+
+	a, err := getApp(tsuruCtx, appName)
+	if err != nil {
+		return err
+	}
+	if unitID, err = killedUnitID(a, unitID, filter); err != nil {
+		return err
+	}
+
+	values := url.Values{}
+	if force, _ := cmd.Flags().GetBool("force"); force {
+		values.Set("force", "true")
+	}
+	request, err := tsuruCtx.NewRequest("DELETE", "/1.12/apps/"+appName+"/units/"+unitID+"?"+values.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	httpResponse, err := tsuruCtx.RawHTTPClient().Do(request)
+	if err != nil {
+		return err
+	}
+	defer httpResponse.Body.Close()
+	if httpResponse.StatusCode >= http.StatusBadRequest {
+		respBody, _ := io.ReadAll(httpResponse.Body)
+		return fmt.Errorf("failed to kill unit %q of app %q: %s", unitID, appName, strings.TrimSpace(string(respBody)))
+	}
+	fmt.Fprintf(tsuruCtx.Stdout, "Unit %q of app %q has been killed.\n", unitID, appName)
+	return nil
+}
+
+// killedUnitID returns the ID of the unit matched by filter whose ID, or short
+// ID as shown by app info, is unitID. Other prefixes of the ID are not
+// accepted, so a mistyped ID never kills another unit.
+func killedUnitID(a *app, unitID string, filter unitFilter) (string, error) {
+	var found []string
+	for _, u := range a.Units {
+		if filter.match(u) && (u.ID == unitID || parser.ShortID(u.ID) == unitID) {
+			found = append(found, u.ID)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return "", fmt.Errorf("unit %q not found on app %q", unitID, a.Name)
+	case 1:
+		return found[0], nil
+	}
+	return "", fmt.Errorf("unit %q is ambiguous on app %q, it may be any of: %s", unitID, a.Name, strings.Join(found, ", "))
+}
@@ -0,0 +1,209 @@
+// Copyright © 2023 tsuru-client authors
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tsuru/tsuru-client/v2/internal/tsuructx"
+)
+
+const appWithUnits = `{"name":"myapp","units":[
+{"ID":"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08","ProcessName":"web","Version":2},
+{"ID":"9f86a7b1c4e85bd7c0f1d1d7f1a2b9c3e4f5a6b7c8d9e0f1a2b3c4d5e6f7a8b9","ProcessName":"web","Version":2},
+{"ID":"60303ae22b998861bce3b28f33eec1be758a213c86c93c076dbe9f558c11c752","ProcessName":"worker","Version":1}]}`
+
+// unitMockServer serves the app and records the requests changing its units,
+// with their query or form.
+func unitMockServer(t *testing.T, appJSON string, requests *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			assert.Equal(t, "/1.0/apps/myapp", r.URL.Path)
+			fmt.Fprintln(w, appJSON)
+			return
+		}
+		assert.NoError(t, r.ParseForm())
+		*requests = append(*requests, fmt.Sprintf("%s %s %s", r.Method, r.URL.Path, r.Form.Encode()))
+		if r.URL.Path == "/1.0/apps/myapp/units" {
+			fmt.Fprintln(w, `{"Message":"---- units changed ----\n"}`)
+		}
+	}))
+}
+
+func TestAppUnitAdd(t *testing.T) {
+	var requests []string
+	mockServer := unitMockServer(t, appWithUnits, &requests)
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+
+	cmd := newAppUnitAddCmd(tsuruCtx)
+	cmd.Flags().Parse([]string{"--process", "worker", "--version", "1"})
+	err := appUnitChangeCmdRun(tsuruCtx, "add", cmd, []string{"myapp", "2"})
+	assert.NoError(t, err)
+	assert.Equal(t, "---- units changed ----\n", tsuruCtx.Stdout.(*strings.Builder).String())
+	assert.Equal(t, []string{"PUT /1.0/apps/myapp/units process=worker&units=2&version=1"}, requests)
+}
+
+func TestAppUnitRemove(t *testing.T) {
+	var requests []string
+	mockServer := unitMockServer(t, appWithUnits, &requests)
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+
+	cmd := newAppUnitRemoveCmd(tsuruCtx)
+	cmd.Flags().Parse([]string{"-a", "myapp", "-p", "web"})
+	err := appUnitChangeCmdRun(tsuruCtx, "remove", cmd, []string{"1"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"DELETE /1.0/apps/myapp/units process=web&units=1&version="}, requests)
+}
+
+func TestAppUnitSet(t *testing.T) {
+	for _, test := range []struct {
+		flags    []string
+		units    string
+		output   string
+		requests []string
+	}{
+		{
+			flags:    []string{"--process", "web"},
+			units:    "5",
+			output:   "Adding 3 units to app \"myapp\" (process web (v2): 2 -> 5).\n---- units changed ----\n",
+			requests: []string{"PUT /1.0/apps/myapp/units process=web&units=3&version=2"},
+		},
+		{
+			flags:    []string{"--process", "web", "--version", "2"},
+			units:    "0",
+			output:   "Removing 2 units from app \"myapp\" (process web (v2): 2 -> 0).\n---- units changed ----\n",
+			requests: []string{"DELETE /1.0/apps/myapp/units process=web&units=2&version=2"},
+		},
+		{
+			flags:  []string{"--process", "worker"},
+			units:  "1",
+			output: "App \"myapp\" already has 1 units (process worker (v1)).\n",
+		},
+	} {
+		var requests []string
+		mockServer := unitMockServer(t, appWithUnits, &requests)
+		tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+		tsuruCtx.SetTargetURL(mockServer.URL)
+
+		cmd := newAppUnitSetCmd(tsuruCtx)
+		cmd.Flags().Parse(test.flags)
+		err := appUnitSetCmdRun(tsuruCtx, cmd, []string{"myapp", test.units})
+		assert.NoError(t, err)
+		assert.Equal(t, test.output, tsuruCtx.Stdout.(*strings.Builder).String())
+		assert.Equal(t, test.requests, requests)
+		mockServer.Close()
+	}
+}
+
+func TestAppUnitSetSingleProcess(t *testing.T) {
+	var requests []string
+	mockServer := unitMockServer(t, `{"name":"myapp","units":[{"ID":"u1","ProcessName":"web","Version":3}]}`, &requests)
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+
+	cmd := newAppUnitSetCmd(tsuruCtx)
+	err := appUnitSetCmdRun(tsuruCtx, cmd, []string{"myapp", "2"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"PUT /1.0/apps/myapp/units process=web&units=1&version=3"}, requests)
+}
+
+func TestAppUnitSetManyProcesses(t *testing.T) {
+	var requests []string
+	mockServer := unitMockServer(t, appWithUnits, &requests)
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+
+	cmd := newAppUnitSetCmd(tsuruCtx)
+	err := appUnitSetCmdRun(tsuruCtx, cmd, []string{"myapp", "2"})
+	assert.EqualError(t, err, `app "myapp" has many processes (web, worker), use --process to choose one`)
+	assert.Nil(t, requests)
+}
+
+func TestAppUnitKill(t *testing.T) {
+	for _, test := range []struct {
+		unitID   string
+		flags    []string
+		expected string
+	}{
+		{"60303ae22b99", nil, "DELETE /1.12/apps/myapp/units/60303ae22b998861bce3b28f33eec1be758a213c86c93c076dbe9f558c11c752 "},
+		{"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08", []string{"-f"}, "DELETE /1.12/apps/myapp/units/9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08 force=true"},
+	} {
+		var requests []string
+		mockServer := unitMockServer(t, appWithUnits, &requests)
+		tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+		tsuruCtx.SetTargetURL(mockServer.URL)
+
+		cmd := newAppUnitKillCmd(tsuruCtx)
+		cmd.Flags().Parse(test.flags)
+		err := appUnitKillCmdRun(tsuruCtx, cmd, []string{"myapp", test.unitID})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{test.expected}, requests)
+		assert.Contains(t, tsuruCtx.Stdout.(*strings.Builder).String(), "has been killed.\n")
+		mockServer.Close()
+	}
+}
+
+func TestAppUnitKillNotFound(t *testing.T) {
+	var requests []string
+	mockServer := unitMockServer(t, appWithUnits, &requests)
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+
+	cmd := newAppUnitKillCmd(tsuruCtx)
+	err := appUnitKillCmdRun(tsuruCtx, cmd, []string{"myapp", "9f86"})
+	assert.EqualError(t, err, `unit "9f86" not found on app "myapp"`)
+
+	cmd = newAppUnitKillCmd(tsuruCtx)
+	err = appUnitKillCmdRun(tsuruCtx, cmd, []string{"myapp", "60303ae22b998861bce3"})
+	assert.EqualError(t, err, `unit "60303ae22b998861bce3" not found on app "myapp"`)
+
+	cmd = newAppUnitKillCmd(tsuruCtx)
+	cmd.Flags().Parse([]string{"--process", "web"})
+	err = appUnitKillCmdRun(tsuruCtx, cmd, []string{"myapp", "60303ae22b99"})
+	assert.EqualError(t, err, `unit "60303ae22b99" not found on app "myapp"`)
+	assert.Nil(t, requests)
+}
+
+func TestAppUnitInvalidArgs(t *testing.T) {
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	for _, test := range []struct {
+		args     []string
+		flags    []string
+		expected string
+	}{
+		{[]string{"myapp", "0"}, nil, `invalid number of units "0", it must be an integer greater than 0`},
+		{[]string{"myapp", "two"}, nil, `invalid number of units "two", it must be an integer greater than 0`},
+		{[]string{"myapp"}, nil, "expected 1 argument after the app name, got 0"},
+		{[]string{"1", "2"}, []string{"-a", "myapp"}, "expected 1 argument after the app name, got 2"},
+	} {
+		cmd := newAppUnitAddCmd(tsuruCtx)
+		cmd.Flags().Parse(test.flags)
+		err := appUnitChangeCmdRun(tsuruCtx, "add", cmd, test.args)
+		assert.EqualError(t, err, test.expected)
+	}
+
+	cmd := newAppUnitSetCmd(tsuruCtx)
+	err := appUnitSetCmdRun(tsuruCtx, cmd, []string{"myapp", "-1"})
+	assert.EqualError(t, err, `invalid number of units "-1", it must be an integer 0 or greater`)
+}
+
+func TestAppUnitIsRegistered(t *testing.T) {
+	appCmd := NewAppCmd(tsuructx.TsuruContextWithConfig(nil))
+	unitCmd, _, err := appCmd.Find([]string{"unit"})
+	if assert.NoError(t, err) && assert.Equal(t, "unit", unitCmd.Name()) {
+		var names []string
+		for _, subCmd := range unitCmd.Commands() {
+			names = append(names, subCmd.Name())
+		}
+		assert.ElementsMatch(t, []string{"add", "remove", "set", "kill"}, names)
+	}
+}
@@ -0,0 +1,259 @@
+// Copyright © 2023 tsuru-client authors
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/tsuru/tsuru-client/v2/internal/tsuructx"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func newAppUpdateCmd(tsuruCtx *tsuructx.TsuruContext) *cobra.Command {
+	appUpdateCmd := &cobra.Command{
+		Use:   "update [APP]",
+		Short: "updates an app",
+		Long: `Updates the attributes of an app: description, platform, plan, team owner,
+pool and tags, and overrides the cpu and memory of its plan.
+Only the attributes given are changed.
+
+The [[--tag]] parameter replaces all the tags of the app, while [[--tag-add]]
+and [[--tag-remove]] add and remove tags keeping the other ones.
+
+The [[--cpu]] and [[--memory]] parameters override the values of the plan of
+the app (eg: 500m or 1 for cpu, 256Mi or 1Gi for memory). Use 0 to remove the
+override, going back to the value of the plan.
+
+The app is restarted by tsuru to use the new attributes, unless [[--no-restart]]
+is given (changing the pool always restarts the app).
+
+The attributes that change are shown (before -> after) before updating the app.`,
+		Example: `$ tsuru app update myapp --plan large
+$ tsuru app update -a myapp --description "my app" --tag-add env:prod --tag-remove env:dev
+$ tsuru app update myapp --cpu 500m --memory 1Gi --no-restart`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return appUpdateCmdRun(tsuruCtx, cmd, args)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return completeAppNames(tsuruCtx, cmd, args, toComplete)
+		},
+		Args: cobra.RangeArgs(0, 1),
+	}
+
+	appUpdateCmd.Flags().StringP("app", "a", "", "The name of the app (may be passed as argument)")
+	appUpdateCmd.Flags().String("platform", "", "the new platform of the app")
+	appUpdateCmd.Flags().StringP("description", "d", "", "the new description of the app")
+	appUpdateCmd.Flags().StringP("plan", "p", "", "the new plan of the app")
+	appUpdateCmd.Flags().StringP("team", "t", "", "the new team owning the app")
+	appUpdateCmd.Flags().StringP("pool", "o", "", "the new pool of the app")
+	appUpdateCmd.Flags().StringArrayP("tag", "g", nil, "replace the tags of the app (may be repeated)")
+	appUpdateCmd.Flags().StringArray("tag-add", nil, "add a tag to the app (may be repeated)")
+	appUpdateCmd.Flags().StringArray("tag-remove", nil, "remove a tag from the app (may be repeated)")
+	appUpdateCmd.Flags().String("cpu", "", "override the cpu of the plan (eg: 500m, 1.5; 0 removes the override)")
+	appUpdateCmd.Flags().String("memory", "", "override the memory of the plan (eg: 256Mi, 1Gi; 0 removes the override)")
+	appUpdateCmd.Flags().Bool("no-restart", false, "Don't restart the app after updating it")
+	return appUpdateCmd
+}
+
+func appUpdateCmdRun(tsuruCtx *tsuructx.TsuruContext, cmd *cobra.Command, args []string) error {
+	appName, err := appNameFromArgsOrFlag(cmd, args)
+	if err != nil {
+		return err
+	}
+	cpuMilli, err := quantityFlag(cmd, "cpu", (*resource.Quantity).MilliValue)
+	if err != nil {
+		return err
+	}
+	memory, err := quantityFlag(cmd, "memory", (*resource.Quantity).Value)
+	if err != nil {
+		return err
+	}
+	if cmd.Flags().Changed("description") && cmd.Flag("description").Value.String() == "" {
+		return fmt.Errorf("--description must not be empty, tsuru does not clear the description of an app")
+	}
+	cmd.SilenceUsage = true
+
+	a, err := getApp(tsuruCtx, appName)
+	if err != nil {
+		return err
+	}
+	values, err := appFormValues(cmd)
+	if err != nil {
+		return err
+	}
+	values.Del("tag")
+	values.Set("platform", cmd.Flag("platform").Value.String())
+
+	var changes []appFieldChange
+	for _, field := range []struct{ name, flag, key, current string }{
+		{"Description", "description", "description", a.Description},
+		{"Platform", "platform", "platform", a.Platform},
+		{"Plan", "plan", "plan", a.Plan.Name},
+		{"Team owner", "team", "teamOwner", a.TeamOwner},
+		{"Pool", "pool", "pool", a.Pool},
+	} {
+		value := values.Get(field.key)
+		if !cmd.Flags().Changed(field.flag) || value == field.current {
+			values.Del(field.key)
+			continue
+		}
+		changes = append(changes, appFieldChange{field.name, field.current, value})
+	}
+
+	if tags := updatedTags(cmd, a.Tags); !equalStrings(tags, a.Tags) {
+		changes = append(changes, appFieldChange{"Tags", strings.Join(a.Tags, ", "), strings.Join(tags, ", ")})
+		values["tag"] = tags
+		if len(tags) == 0 {
+			values.Set("tag", "") // an empty tag removes all of them
+		}
+	}
+
+	if cpuMilli != nil {
+		before, after := int64(a.Plan.CPUMilli), *cpuMilli
+		if a.Plan.Override.CPUMilli != nil {
+			before = int64(*a.Plan.Override.CPUMilli)
+		}
+		if after == 0 {
+			after = int64(a.Plan.CPUMilli)
+		}
+		if before != after {
+			changes = append(changes, appFieldChange{"CPU", cpuString(before), cpuString(after)})
+			values.Set("planoverride.cpumilli", strconv.FormatInt(*cpuMilli, 10))
+		}
+	}
+	if memory != nil {
+		before, after := a.Plan.Memory, *memory
+		if a.Plan.Override.Memory != nil {
+			before = *a.Plan.Override.Memory
+		}
+		if after == 0 {
+			after = a.Plan.Memory
+		}
+		if before != after {
+			changes = append(changes, appFieldChange{"Memory", memoryString(before), memoryString(after)})
+			values.Set("planoverride.memory", strconv.FormatInt(*memory, 10))
+		}
+	}
+
+	if len(changes) == 0 {
+		fmt.Fprintf(tsuruCtx.Stdout, "Nothing to update on app %q.\n", appName)
+		return nil
+	}
+	printAppChanges(tsuruCtx.Stdout, appName, changes)
+	if noRestart, _ := cmd.Flags().GetBool("no-restart"); noRestart {
+		values.Set("noRestart", "true")
+	}
+
+	request, err := tsuruCtx.NewRequest("PUT", "/apps/"+appName, strings.NewReader(values.Encode()))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	httpResponse, err := tsuruCtx.RawHTTPClient().Do(request)
+	if err != nil {
+		return err
+	}
+	defer httpResponse.Body.Close()
+	if httpResponse.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("app %q not found", appName)
+	}
+	if httpResponse.StatusCode >= http.StatusBadRequest {
+		respBody, _ := io.ReadAll(httpResponse.Body)
+		return fmt.Errorf("failed to update app %q: %s", appName, strings.TrimSpace(string(respBody)))
+	}
+	if _, err = streamOutput(tsuruCtx.Stdout, httpResponse.Body); err != nil {
+		return fmt.Errorf("failed to update app %q: %w", appName, err)
+	}
+	fmt.Fprintf(tsuruCtx.Stdout, "App %q has been updated!\n", appName)
+	return nil
+}
+
+// quantityFlag parses the flag as a resource quantity (eg: 500m, 1Gi),
+// returning nil when the flag was not given.
+func quantityFlag(cmd *cobra.Command, flagName string, value func(*resource.Quantity) int64) (*int64, error) {
+	str := cmd.Flag(flagName).Value.String()
+	if str == "" {
+		return nil, nil
+	}
+	qt, err := resource.ParseQuantity(str)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value %q for --%s: %w", str, flagName, err)
+	}
+	if qt.Sign() < 0 {
+		return nil, fmt.Errorf("invalid value %q for --%s: must not be negative", str, flagName)
+	}
+	v := value(&qt)
+	return &v, nil
+}
+
+// updatedTags returns the tags of the app after applying --tag (replacing
+// all of them), --tag-add and --tag-remove.
+func updatedTags(cmd *cobra.Command, current []string) []string {
+	tags := current
+	if cmd.Flags().Changed("tag") {
+		tags, _ = cmd.Flags().GetStringArray("tag")
+	}
+	added, _ := cmd.Flags().GetStringArray("tag-add")
+	removed, _ := cmd.Flags().GetStringArray("tag-remove")
+	isRemoved := map[string]bool{}
+	for _, tag := range removed {
+		isRemoved[tag] = true
+	}
+
+	result := []string{}
+	seen := map[string]bool{}
+	for _, tag := range append(append([]string{}, tags...), added...) {
+		if tag == "" || seen[tag] || isRemoved[tag] {
+			continue
+		}
+		seen[tag] = true
+		result = append(result, tag)
+	}
+	return result
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func cpuString(milli int64) string {
+	return resource.NewMilliQuantity(milli, resource.DecimalSI).String()
+}
+
+func memoryString(bytes int64) string {
+	return resource.NewQuantity(bytes, resource.BinarySI).String()
+}
+
+type appFieldChange struct {
+	Field  string
+	Before string
+	After  string
+}
+
+func printAppChanges(out io.Writer, appName string, changes []appFieldChange) {
+	width := 0
+	for _, c := range changes {
+		if len(c.Field) > width {
+			width = len(c.Field)
+		}
+	}
+	fmt.Fprintf(out, "Updating app %q:\n", appName)
+	for _, c := range changes {
+		fmt.Fprintf(out, "  %-*s %s -> %s\n", width+1, c.Field+":", noneIfEmpty(c.Before), noneIfEmpty(c.After))
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright © 2023 tsuru-client authors
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tsuru/tsuru-client/v2/internal/tsuructx"
+)
+
+const appToUpdate = `{"name":"myapp","platform":"python","description":"my app","teamowner":"myteam","pool":"mypool",
+"tags":["env:dev","team:a"],"plan":{"name":"small","memory":268435456,"cpumilli":250,"override":{"memory":null,"cpumilli":500}}}`
+
+func updateMockServer(t *testing.T, form *url.Values) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/1.0/apps/myapp", r.URL.Path)
+		switch r.Method {
+		case "GET":
+			fmt.Fprintln(w, appToUpdate)
+		case "PUT":
+			assert.Equal(t, "application/x-www-form-urlencoded", r.Header.Get("Content-Type"))
+			assert.NoError(t, r.ParseForm())
+			*form = r.PostForm
+			fmt.Fprintln(w, `{"Message":"restarting app\n"}`)
+		}
+	}))
+}
+
+func TestAppUpdateRun(t *testing.T) {
+	var form url.Values
+	mockServer := updateMockServer(t, &form)
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+
+	cmd := newAppUpdateCmd(tsuruCtx)
+	cmd.Flags().Parse([]string{"--plan", "large", "--pool", "mypool", "--tag-add", "env:prod", "--tag-remove", "env:dev", "--cpu", "1", "--memory", "1Gi", "--no-restart"})
+	err := appUpdateCmdRun(tsuruCtx, cmd, []string{"myapp"})
+	assert.NoError(t, err)
+	assert.Equal(t, url.Values{
+		"plan":                  {"large"},
+		"tag":                   {"team:a", "env:prod"},
+		"planoverride.cpumilli": {"1000"},
+		"planoverride.memory":   {"1073741824"},
+		"noRestart":             {"true"},
+	}, form)
+	expected := `Updating app "myapp":
+  Plan:   small -> large
+  Tags:   env:dev, team:a -> team:a, env:prod
+  CPU:    500m -> 1
+  Memory: 256Mi -> 1Gi
+restarting app
+App "myapp" has been updated!
+`
+	assert.Equal(t, expected, tsuruCtx.Stdout.(*strings.Builder).String())
+}
+
+func TestAppUpdateRunRemoveAllTagsAndOverride(t *testing.T) {
+	var form url.Values
+	mockServer := updateMockServer(t, &form)
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+
+	cmd := newAppUpdateCmd(tsuruCtx)
+	cmd.Flags().Parse([]string{"-a", "myapp", "--tag-remove", "env:dev", "--tag-remove", "team:a", "--cpu", "0", "-d", "new description"})
+	err := appUpdateCmdRun(tsuruCtx, cmd, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, url.Values{
+		"description":           {"new description"},
+		"tag":                   {""},
+		"planoverride.cpumilli": {"0"},
+	}, form)
+	expected := `Updating app "myapp":
+  Description: my app -> new description
+  Tags:        env:dev, team:a -> (none)
+  CPU:         500m -> 250m
+restarting app
+App "myapp" has been updated!
+`
+	assert.Equal(t, expected, tsuruCtx.Stdout.(*strings.Builder).String())
+}
+
+func TestAppUpdateRunEmptyDescription(t *testing.T) {
+	var form url.Values
+	mockServer := updateMockServer(t, &form)
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+
+	cmd := newAppUpdateCmd(tsuruCtx)
+	cmd.Flags().Parse([]string{"--description", ""})
+	err := appUpdateCmdRun(tsuruCtx, cmd, []string{"myapp"})
+	assert.EqualError(t, err, "--description must not be empty, tsuru does not clear the description of an app")
+	assert.Nil(t, form)
+}
+
+func TestAppUpdateRunNothingToUpdate(t *testing.T) {
+	var form url.Values
+	mockServer := updateMockServer(t, &form)
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+
+	cmd := newAppUpdateCmd(tsuruCtx)
+	cmd.Flags().Parse([]string{"--platform", "python", "--tag", "env:dev", "--tag", "team:a", "--cpu", "500m"})
+	err := appUpdateCmdRun(tsuruCtx, cmd, []string{"myapp"})
+	assert.NoError(t, err)
+	assert.Nil(t, form)
+	assert.Equal(t, "Nothing to update on app \"myapp\".\n", tsuruCtx.Stdout.(*strings.Builder).String())
+}
+
+func TestAppUpdateRunInvalidQuantity(t *testing.T) {
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	cmd := newAppUpdateCmd(tsuruCtx)
+	cmd.Flags().Parse([]string{"--memory", "lots"})
+	err := appUpdateCmdRun(tsuruCtx, cmd, []string{"myapp"})
+	assert.ErrorContains(t, err, `invalid value "lots" for --memory`)
+
+	cmd = newAppUpdateCmd(tsuruCtx)
+	cmd.Flags().Parse([]string{"--cpu", "-1"})
+	err = appUpdateCmdRun(tsuruCtx, cmd, []string{"myapp"})
+	assert.EqualError(t, err, `invalid value "-1" for --cpu: must not be negative`)
+}
+
+func TestAppUpdateRunServerError(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			fmt.Fprintln(w, appToUpdate)
+			return
+		}
+		http.Error(w, "plan not found", http.StatusBadRequest)
+	}))
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+
+	cmd := newAppUpdateCmd(tsuruCtx)
+	cmd.Flags().Parse([]string{"--plan", "huge"})
+	err := appUpdateCmdRun(tsuruCtx, cmd, []string{"myapp"})
+	assert.EqualError(t, err, `failed to update app "myapp": plan not found`)
+}
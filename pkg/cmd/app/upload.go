@@ -0,0 +1,219 @@
+// Copyright © 2023 tsuru-client authors
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/tsuru/tsuru-client/v2/internal/parser"
+)
+
+// deployBody is the request body of a deploy. Archives sent to many apps are
+// kept on a temporary file, so they are built once. Otherwise, the archive is
+// streamed, built again on every upload (retrying a failed upload builds it
+// once more), after a first pass measuring the size of the body.
+type deployBody struct {
+	fsys          afero.Fs
+	fileName      string // the temporary file keeping the body, if any
+	data          []byte // the body, when there's no archive
+	values        url.Values
+	streamArchive func(io.Writer) error // writes the streamed archive
+	boundary      string
+	size          int64
+	contentType   string
+}
+
+// newDeployBody returns the body with the form values and the archive (if
+// writeArchive is not nil). When streamArchive is not nil, it writes the
+// archive on every upload, while writeArchive is only used to measure it.
+// The body must be closed to remove its file.
+func newDeployBody(fsys afero.Fs, values url.Values, writeArchive, streamArchive func(io.Writer) error) (*deployBody, error) {
+	if writeArchive == nil {
+		data := []byte(values.Encode())
+		return &deployBody{data: data, size: int64(len(data)), contentType: "application/x-www-form-urlencoded"}, nil
+	}
+
+	if streamArchive != nil {
+		var size byteCounter
+		mw := multipart.NewWriter(&size)
+		if err := writeDeployMultipart(mw, values, writeArchive); err != nil {
+			return nil, err
+		}
+		return &deployBody{
+			values:        values,
+			streamArchive: streamArchive,
+			boundary:      mw.Boundary(),
+			size:          int64(size),
+			contentType:   mw.FormDataContentType(),
+		}, nil
+	}
+
+	f, err := afero.TempFile(fsys, "", "tsuru-deploy-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create a temporary file for the archive: %w", err)
+	}
+	defer f.Close()
+	body := &deployBody{fsys: fsys, fileName: f.Name()}
+	mw := multipart.NewWriter(f)
+	body.contentType = mw.FormDataContentType()
+	if err = writeDeployMultipart(mw, values, writeArchive); err != nil {
+		body.Close()
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		body.Close()
+		return nil, err
+	}
+	body.size = fi.Size()
+	return body, nil
+}
+
+// open returns a new reader of the whole body.
+func (b *deployBody) open() (io.ReadCloser, error) {
+	switch {
+	case b.streamArchive != nil:
+		pipeReader, pipeWriter := io.Pipe()
+		mw := multipart.NewWriter(pipeWriter)
+		if err := mw.SetBoundary(b.boundary); err != nil {
+			return nil, err
+		}
+		go func() {
+			pipeWriter.CloseWithError(writeDeployMultipart(mw, b.values, b.streamArchive))
+		}()
+		return pipeReader, nil
+	case b.fileName != "":
+		return b.fsys.Open(b.fileName)
+	}
+	return io.NopCloser(bytes.NewReader(b.data)), nil
+}
+
+// hasArchive returns whether the body has an archive, so it's worth showing
+// the progress of its upload.
+func (b *deployBody) hasArchive() bool {
+	return b.streamArchive != nil || b.fileName != ""
+}
+
+func (b *deployBody) Close() error {
+	if b.fileName == "" {
+		return nil
+	}
+	return b.fsys.Remove(b.fileName)
+}
+
+// byteCounter is a writer counting the bytes written to it.
+type byteCounter int64
+
+func (c *byteCounter) Write(p []byte) (int, error) {
+	*c += byteCounter(len(p))
+	return len(p), nil
+}
+
+// uploadProgress reports how much of an upload was sent: as a progress bar
+// redrawn on terminals, or as a plain line from time to time otherwise.
+type uploadProgress struct {
+	out   io.Writer
+	tty   bool
+	total int64
+	now   func() time.Time
+
+	sent     int64
+	start    time.Time
+	lastShow time.Time
+	done     bool
+}
+
+func newUploadProgress(out io.Writer, tty bool, total int64) *uploadProgress {
+	return &uploadProgress{out: out, tty: tty, total: total, now: time.Now}
+}
+
+// Reader returns r counting the bytes read from it as sent.
+func (p *uploadProgress) Reader(r io.ReadCloser) io.ReadCloser {
+	return &progressReader{ReadCloser: r, progress: p}
+}
+
+func (p *uploadProgress) add(n int) {
+	now := p.now()
+	if p.start.IsZero() {
+		p.start, p.lastShow = now, now
+	}
+	p.sent += int64(n)
+
+	interval := 5 * time.Second
+	if p.tty {
+		interval = 200 * time.Millisecond
+	}
+	if n > 0 && now.Sub(p.lastShow) >= interval {
+		p.lastShow = now
+		p.show(now)
+	}
+}
+
+func (p *uploadProgress) finish() {
+	if p.done {
+		return
+	}
+	p.done = true
+	now := p.now()
+	if p.start.IsZero() {
+		p.start = now
+	}
+	if p.tty {
+		p.show(now)
+		fmt.Fprintln(p.out)
+	}
+	elapsed := now.Sub(p.start)
+	fmt.Fprintf(p.out, "Uploaded %s in %s (%s/s)\n", parser.BytesValue(p.sent), elapsed.Round(time.Second), parser.BytesValue(p.rate(elapsed)))
+}
+
+func (p *uploadProgress) rate(elapsed time.Duration) int64 {
+	if elapsed <= 0 {
+		return p.sent
+	}
+	return int64(float64(p.sent) / elapsed.Seconds())
+}
+
+func (p *uploadProgress) show(now time.Time) {
+	elapsed := now.Sub(p.start)
+	rate := p.rate(elapsed)
+	percent := 100
+	if p.total > 0 {
+		percent = int(p.sent * 100 / p.total)
+	}
+	eta := "-"
+	if rate > 0 && p.sent < p.total {
+		eta = time.Duration(float64(p.total-p.sent) / float64(rate) * float64(time.Second)).Round(time.Second).String()
+	}
+
+	if !p.tty {
+		fmt.Fprintf(p.out, "Uploaded %s of %s (%d%%) at %s/s, ETA %s\n", parser.BytesValue(p.sent), parser.BytesValue(p.total), percent, parser.BytesValue(rate), eta)
+		return
+	}
+	const barWidth = 30
+	filled := percent * barWidth / 100
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", barWidth-filled)
+	fmt.Fprintf(p.out, "\r[%s] %s/%s %3d%% %s/s ETA %s\033[K", bar, parser.BytesValue(p.sent), parser.BytesValue(p.total), percent, parser.BytesValue(rate), eta)
+}
+
+type progressReader struct {
+	io.ReadCloser
+	progress *uploadProgress
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.progress.add(n)
+	if err == io.EOF {
+		r.progress.finish()
+	}
+	return n, err
+}
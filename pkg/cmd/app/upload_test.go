@@ -0,0 +1,127 @@
+// Copyright © 2023 tsuru-client authors
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUploadProgressPlain(t *testing.T) {
+	start := time.Date(2023, 6, 20, 10, 0, 0, 0, time.UTC)
+	now := start
+	out := strings.Builder{}
+	p := newUploadProgress(&out, false, 4096)
+	p.now = func() time.Time { return now }
+	r := p.Reader(io.NopCloser(strings.NewReader(strings.Repeat("a", 4096))))
+
+	buf := make([]byte, 1024)
+	for _, elapsed := range []time.Duration{0, 2 * time.Second, 5 * time.Second, 6 * time.Second, 6 * time.Second} {
+		now = start.Add(elapsed)
+		r.Read(buf)
+	}
+	assert.Equal(t, `Uploaded 3.0KiB of 4.0KiB (75%) at 614B/s, ETA 2s
+Uploaded 4.0KiB in 6s (682B/s)
+`, out.String())
+}
+
+func TestUploadProgressTTY(t *testing.T) {
+	start := time.Date(2023, 6, 20, 10, 0, 0, 0, time.UTC)
+	now := start
+	out := strings.Builder{}
+	p := newUploadProgress(&out, true, 4096)
+	p.now = func() time.Time { return now }
+	r := p.Reader(io.NopCloser(strings.NewReader(strings.Repeat("a", 4096))))
+
+	buf := make([]byte, 1024)
+	for _, elapsed := range []time.Duration{0, time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second} {
+		now = start.Add(elapsed)
+		r.Read(buf)
+	}
+	assert.Equal(t, "\r[===============               ] 2.0KiB/4.0KiB  50% 2.0KiB/s ETA 1s\033[K"+
+		"\r[======================        ] 3.0KiB/4.0KiB  75% 1.5KiB/s ETA 1s\033[K"+
+		"\r[==============================] 4.0KiB/4.0KiB 100% 1.0KiB/s ETA -\033[K"+
+		"\r[==============================] 4.0KiB/4.0KiB 100% 1.0KiB/s ETA -\033[K\n"+
+		"Uploaded 4.0KiB in 4s (1.0KiB/s)\n", out.String())
+}
+
+func TestDeployBody(t *testing.T) {
+	fsys := afero.NewMemMapFs()
+	values := url.Values{"origin": []string{"app-deploy"}}
+	body, err := newDeployBody(fsys, values, func(w io.Writer) error {
+		_, err := w.Write([]byte("archive-content"))
+		return err
+	}, nil)
+	assert.NoError(t, err)
+	assert.Contains(t, body.contentType, "multipart/form-data; boundary=")
+
+	for i := 0; i < 2; i++ { // can be read many times
+		r, err := body.open()
+		assert.NoError(t, err)
+		data, _ := io.ReadAll(r)
+		r.Close()
+		assert.Equal(t, body.size, int64(len(data)))
+		assert.Contains(t, string(data), "archive-content")
+	}
+
+	assert.NoError(t, body.Close())
+	_, err = fsys.Stat(body.fileName)
+	assert.Error(t, err)
+
+	body, err = newDeployBody(fsys, values, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "application/x-www-form-urlencoded", body.contentType)
+	assert.Equal(t, []byte("origin=app-deploy"), body.data)
+}
+
+func TestDeployBodyStreamed(t *testing.T) {
+	fsys := afero.NewMemMapFs()
+	values := url.Values{"origin": []string{"app-deploy"}}
+	writes := 0
+	body, err := newDeployBody(fsys, values, func(w io.Writer) error {
+		_, err := w.Write([]byte("archive-content"))
+		return err
+	}, func(w io.Writer) error {
+		writes++
+		_, err := w.Write([]byte("archive-content"))
+		return err
+	})
+	assert.NoError(t, err)
+	assert.Contains(t, body.contentType, "multipart/form-data; boundary="+body.boundary)
+	assert.Equal(t, "", body.fileName)
+	assert.True(t, body.hasArchive())
+
+	for i := 0; i < 2; i++ { // the archive is written again on every read
+		r, err := body.open()
+		assert.NoError(t, err)
+		data, _ := io.ReadAll(r)
+		r.Close()
+		assert.Equal(t, body.size, int64(len(data)))
+		assert.Contains(t, string(data), "archive-content")
+		assert.Contains(t, string(data), body.boundary)
+	}
+	assert.Equal(t, 2, writes)
+	assert.NoError(t, body.Close())
+}
+
+func TestDeployBodyStreamedError(t *testing.T) {
+	body, err := newDeployBody(afero.NewMemMapFs(), url.Values{}, func(w io.Writer) error {
+		return nil
+	}, func(w io.Writer) error {
+		return fmt.Errorf("file changed")
+	})
+	assert.NoError(t, err)
+	r, err := body.open()
+	assert.NoError(t, err)
+	_, err = io.ReadAll(r)
+	assert.EqualError(t, err, "file changed")
+}
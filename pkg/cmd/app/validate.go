@@ -0,0 +1,350 @@
+// Copyright © 2023 tsuru-client authors
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/tsuru/tsuru-client/v2/internal/archiver"
+	"github.com/tsuru/tsuru-client/v2/internal/tsuructx"
+	"gopkg.in/yaml.v3"
+)
+
+// tsuruYamlNames are the names of the tsuru.yaml file, in the order tsuru
+// looks for them on the root of the deployed files.
+var tsuruYamlNames = []string{"tsuru.yaml", "tsuru.yml", "app.yaml", "app.yml"}
+
+// procfileRegexp matches process declarations, the same way tsuru does.
+var procfileRegexp = regexp.MustCompile(`^([A-Za-z0-9_-]+):\s*(.+)$`)
+
+func newAppValidateCmd(tsuruCtx *tsuructx.TsuruContext) *cobra.Command {
+	appValidateCmd := &cobra.Command{
+		Use:   "validate [file-or-dir ...]",
+		Short: "validate the tsuru.yaml and Procfile of the files to deploy",
+		Long: `Validate locally the files that would be deployed (the current directory by
+default): the syntax of tsuru.yaml (hooks, healthcheck and kubernetes processes)
+and the process declarations on the Procfile. It also warns when the ignore file
+leaves the Procfile out, or when --files-only would place files with the same
+name at the same place.
+
+The same validation is done by "tsuru app deploy" before uploading the files.`,
+		Example: `$ tsuru app validate
+$ tsuru app validate mysite/
+$ tsuru app validate --files-only ./my-code/main.go ./tsuru_stuff/Procfile`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return appValidateCmdRun(tsuruCtx, cmd, args)
+		},
+	}
+
+	appValidateCmd.Flags().BoolP("files-only", "f", false, "Validate as deploying files only, ignoring their base directories")
+	appValidateCmd.Flags().Bool("dockerfile", false, "Validate as deploying with a container file (the Procfile is optional)")
+	return appValidateCmd
+}
+
+func appValidateCmdRun(tsuruCtx *tsuructx.TsuruContext, cmd *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		args = []string{"."}
+	}
+	cmd.SilenceUsage = true
+
+	filesOnly, _ := cmd.Flags().GetBool("files-only")
+	dockerfile, _ := cmd.Flags().GetBool("dockerfile")
+	opts := archiver.DefaultOptions(nil)
+	if dockerfile {
+		opts.IgnoreFiles = []string{dockerIgnoreFile(tsuruCtx.Fs, args)}
+	}
+	opts.Stats = &archiver.Stats{}
+	if err := archiver.Archive(tsuruCtx.Fs, io.Discard, filesOnly, args, opts); err != nil {
+		return err
+	}
+
+	problems := validateAppFiles(tsuruCtx.Fs, opts.Stats, validateOptions{
+		filesOnly:   filesOnly,
+		dockerfile:  dockerfile,
+		ignoreFiles: opts.IgnoreFiles,
+	})
+	printValidationProblems(tsuruCtx.Stdout, problems)
+	if len(problems) == 0 {
+		fmt.Fprintln(tsuruCtx.Stdout, "No problems found.")
+	}
+	return problems.Err()
+}
+
+type validationProblem struct {
+	File    string
+	Error   bool // warnings otherwise
+	Message string
+}
+
+func (p validationProblem) String() string {
+	level := "WARNING"
+	if p.Error {
+		level = "ERROR"
+	}
+	if p.File == "" {
+		return fmt.Sprintf("%s: %s", level, p.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", level, p.File, p.Message)
+}
+
+type validationProblems []validationProblem
+
+// Err returns an error when any of the problems is an error.
+func (ps validationProblems) Err() error {
+	var errs int
+	for _, p := range ps {
+		if p.Error {
+			errs++
+		}
+	}
+	if errs > 0 {
+		return fmt.Errorf("found %d error(s) on the app files", errs)
+	}
+	return nil
+}
+
+func (ps *validationProblems) add(file string, isError bool, format string, args ...any) {
+	*ps = append(*ps, validationProblem{File: file, Error: isError, Message: fmt.Sprintf(format, args...)})
+}
+
+func printValidationProblems(out io.Writer, problems validationProblems) {
+	for _, p := range problems {
+		fmt.Fprintln(out, p)
+	}
+}
+
+type validateOptions struct {
+	filesOnly   bool
+	dockerfile  bool // the Procfile is optional with container files
+	ignoreFiles []string
+}
+
+// validateAppFiles validates the tsuru.yaml and Procfile of the archive
+// described by stats (with paths read from fsys).
+func validateAppFiles(fsys afero.Fs, stats *archiver.Stats, opts validateOptions) validationProblems {
+	var problems validationProblems
+	added := map[string]string{} // name on the archive -> path
+	for _, f := range stats.Files {
+		name := strings.TrimSuffix(f.Name, "/")
+		switch f.Status {
+		case "added":
+			added[name] = f.Path
+		case "ignored":
+			if name == "Procfile" {
+				problems.add(f.Path, false, "the Procfile is excluded by %s, so it won't be deployed", strings.Join(opts.ignoreFiles, ", "))
+			}
+		case "duplicated":
+			msg := "another file with the same name is already placed at %q, so it won't be deployed"
+			if opts.filesOnly {
+				msg += " (--files-only places every file on the root)"
+			}
+			problems.add(f.Path, false, msg, name)
+		}
+	}
+
+	var processes []string
+	if p, ok := added["Procfile"]; ok {
+		processes = validateProcfile(fsys, p, &problems)
+	} else if !opts.dockerfile {
+		problems.add("", false, "no Procfile found on the root of the deployed files, the platform's default process is used (if any)")
+	}
+
+	for _, name := range tsuruYamlNames {
+		if p, ok := added[name]; ok {
+			validateTsuruYaml(fsys, p, processes, &problems)
+			break
+		}
+	}
+	return problems
+}
+
+func validateProcfile(fsys afero.Fs, path string, problems *validationProblems) []string {
+	data, err := afero.ReadFile(fsys, path)
+	if err != nil {
+		problems.add(path, true, "%s", err)
+		return nil
+	}
+	var processes []string
+	seen := map[string]bool{}
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		m := procfileRegexp.FindStringSubmatch(line)
+		if m == nil {
+			problems.add(path, false, "line %d is not a valid process declaration (name: command) and is ignored", i+1)
+			continue
+		}
+		if seen[m[1]] {
+			problems.add(path, false, "line %d declares process %q again, overriding the previous declaration", i+1, m[1])
+			continue
+		}
+		seen[m[1]] = true
+		processes = append(processes, m[1])
+	}
+	if len(processes) == 0 {
+		problems.add(path, true, "no process declared")
+	}
+	return processes
+}
+
+type tsuruYaml struct {
+	Hooks *struct {
+		Restart struct {
+			Before []string `yaml:"before"`
+			After  []string `yaml:"after"`
+		} `yaml:"restart"`
+		Build []string `yaml:"build"`
+	} `yaml:"hooks"`
+	Healthcheck *struct {
+		Path                 string            `yaml:"path"`
+		Method               string            `yaml:"method"`
+		Status               int               `yaml:"status"`
+		Scheme               string            `yaml:"scheme"`
+		Command              []string          `yaml:"command"`
+		Headers              map[string]string `yaml:"headers"`
+		Match                string            `yaml:"match"`
+		RouterBody           string            `yaml:"router_body"`
+		UseInRouter          bool              `yaml:"use_in_router"`
+		ForceRestart         bool              `yaml:"force_restart"`
+		AllowedFailures      int               `yaml:"allowed_failures"`
+		IntervalSeconds      int               `yaml:"interval_seconds"`
+		TimeoutSeconds       int               `yaml:"timeout_seconds"`
+		DeployTimeoutSeconds int               `yaml:"deploy_timeout_seconds"`
+	} `yaml:"healthcheck"`
+	Kubernetes *struct {
+		Groups map[string]map[string]struct {
+			Ports []struct {
+				Name       string `yaml:"name"`
+				Protocol   string `yaml:"protocol"`
+				Port       int    `yaml:"port"`
+				TargetPort int    `yaml:"target_port"`
+			} `yaml:"ports"`
+		} `yaml:"groups"`
+	} `yaml:"kubernetes"`
+}
+
+// decodeTsuruYaml decodes data, ignoring the fields not checked by validate.
+func decodeTsuruYaml(data []byte) (y tsuruYaml, err error) {
+	if err = yaml.NewDecoder(bytes.NewReader(data)).Decode(&y); errors.Is(err, io.EOF) { // empty file
+		err = nil
+	}
+	return y, err
+}
+
+func validateTsuruYaml(fsys afero.Fs, path string, processes []string, problems *validationProblems) {
+	data, err := afero.ReadFile(fsys, path)
+	if err != nil {
+		problems.add(path, true, "%s", err)
+		return
+	}
+	y, err := decodeTsuruYaml(data)
+	if err != nil {
+		problems.add(path, true, "%s", strings.TrimPrefix(err.Error(), "yaml: "))
+		return
+	}
+	if y.Hooks != nil {
+		for _, hooks := range []struct {
+			field    string
+			commands []string
+		}{
+			{"hooks.restart.before", y.Hooks.Restart.Before},
+			{"hooks.restart.after", y.Hooks.Restart.After},
+			{"hooks.build", y.Hooks.Build},
+		} {
+			for i, command := range hooks.commands {
+				if strings.TrimSpace(command) == "" {
+					problems.add(path, true, "%s[%d]: empty command", hooks.field, i)
+				}
+			}
+		}
+	}
+
+	if hc := y.Healthcheck; hc != nil {
+		if hc.Path == "" && len(hc.Command) == 0 {
+			problems.add(path, false, "healthcheck: neither path nor command is set, so it is disabled")
+		}
+		if hc.Path != "" && len(hc.Command) > 0 {
+			problems.add(path, false, "healthcheck: both path and command are set, command is ignored")
+		}
+		if hc.Path != "" && !strings.HasPrefix(hc.Path, "/") {
+			problems.add(path, false, "healthcheck.path: %q should start with /", hc.Path)
+		}
+		if hc.Method != "" && strings.ToUpper(hc.Method) != http.MethodGet {
+			problems.add(path, true, "healthcheck.method: only GET is supported, got %q", hc.Method)
+		}
+		if scheme := strings.ToLower(hc.Scheme); scheme != "" && scheme != "http" && scheme != "https" {
+			problems.add(path, true, "healthcheck.scheme: must be http or https, got %q", hc.Scheme)
+		}
+		if hc.Status != 0 && (hc.Status < 100 || hc.Status > 599) {
+			problems.add(path, true, "healthcheck.status: %d is not a valid HTTP status", hc.Status)
+		}
+		if hc.Match != "" {
+			if _, err := regexp.Compile(hc.Match); err != nil {
+				problems.add(path, true, "healthcheck.match: %s", err)
+			}
+		}
+		if hc.UseInRouter && hc.Path == "" {
+			problems.add(path, false, "healthcheck.use_in_router: routers only check paths, not commands")
+		}
+		for _, field := range []struct {
+			name  string
+			value int
+		}{
+			{"allowed_failures", hc.AllowedFailures},
+			{"interval_seconds", hc.IntervalSeconds},
+			{"timeout_seconds", hc.TimeoutSeconds},
+			{"deploy_timeout_seconds", hc.DeployTimeoutSeconds},
+		} {
+			if field.value < 0 {
+				problems.add(path, true, "healthcheck.%s: must not be negative, got %d", field.name, field.value)
+			}
+		}
+	}
+
+	if y.Kubernetes != nil {
+		declared := map[string]bool{}
+		for _, p := range processes {
+			declared[p] = true
+		}
+		for _, group := range sortedKeys(y.Kubernetes.Groups) {
+			procs := y.Kubernetes.Groups[group]
+			for _, proc := range sortedKeys(procs) {
+				config := procs[proc]
+				if len(processes) > 0 && !declared[proc] {
+					problems.add(path, false, "kubernetes.groups.%s: process %q is not declared on the Procfile", group, proc)
+				}
+				for i, port := range config.Ports {
+					field := fmt.Sprintf("kubernetes.groups.%s.%s.ports[%d]", group, proc, i)
+					if port.Port < 0 || port.Port > 65535 || port.TargetPort < 0 || port.TargetPort > 65535 {
+						problems.add(path, true, "%s: ports must be between 1 and 65535", field)
+					}
+					if protocol := strings.ToUpper(port.Protocol); protocol != "" && protocol != "TCP" && protocol != "UDP" {
+						problems.add(path, true, "%s: protocol must be TCP or UDP, got %q", field, port.Protocol)
+					}
+				}
+			}
+		}
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,159 @@
+// Copyright © 2023 tsuru-client authors
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/tsuru/tsuru-client/v2/internal/tsuructx"
+)
+
+func TestAppValidateRun(t *testing.T) {
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	afero.WriteFile(tsuruCtx.Fs, "mysite/Procfile", []byte("web: ./run --port $PORT\n# the workers\nworker: ./work\n"), 0644)
+	afero.WriteFile(tsuruCtx.Fs, "mysite/tsuru.yaml", []byte(`hooks:
+  build:
+    - make assets
+  restart:
+    before:
+      - ./migrate
+healthcheck:
+  path: /healthcheck
+  scheme: https
+  status: 200
+  allowed_failures: 5
+kubernetes:
+  groups:
+    mysite:
+      web:
+        ports:
+          - port: 8080
+            protocol: TCP
+`), 0644)
+
+	cmd := newAppValidateCmd(tsuruCtx)
+	err := appValidateCmdRun(tsuruCtx, cmd, []string{"mysite"})
+	assert.NoError(t, err)
+	assert.Equal(t, "No problems found.\n", tsuruCtx.Stdout.(*strings.Builder).String())
+}
+
+func TestAppValidateRunProblems(t *testing.T) {
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	afero.WriteFile(tsuruCtx.Fs, "mysite/Procfile", []byte("web: ./run\nweb ./other\nweb: ./again\n"), 0644)
+	afero.WriteFile(tsuruCtx.Fs, "mysite/tsuru.yml", []byte(`hooks:
+  build:
+    - ""
+healthcheck:
+  path: healthcheck
+  method: post
+  scheme: tcp
+  status: 20
+  match: "("
+  timeout_seconds: -1
+kubernetes:
+  groups:
+    mysite:
+      worker:
+        ports:
+          - port: 70000
+            protocol: SCTP
+`), 0644)
+
+	cmd := newAppValidateCmd(tsuruCtx)
+	err := appValidateCmdRun(tsuruCtx, cmd, []string{"mysite"})
+	assert.EqualError(t, err, "found 8 error(s) on the app files")
+	expected := `WARNING: mysite/Procfile: line 2 is not a valid process declaration (name: command) and is ignored
+WARNING: mysite/Procfile: line 3 declares process "web" again, overriding the previous declaration
+ERROR: mysite/tsuru.yml: hooks.build[0]: empty command
+WARNING: mysite/tsuru.yml: healthcheck.path: "healthcheck" should start with /
+ERROR: mysite/tsuru.yml: healthcheck.method: only GET is supported, got "post"
+ERROR: mysite/tsuru.yml: healthcheck.scheme: must be http or https, got "tcp"
+ERROR: mysite/tsuru.yml: healthcheck.status: 20 is not a valid HTTP status
+ERROR: mysite/tsuru.yml: healthcheck.match: error parsing regexp: missing closing ): ` + "`(`" + `
+ERROR: mysite/tsuru.yml: healthcheck.timeout_seconds: must not be negative, got -1
+WARNING: mysite/tsuru.yml: kubernetes.groups.mysite: process "worker" is not declared on the Procfile
+ERROR: mysite/tsuru.yml: kubernetes.groups.mysite.worker.ports[0]: ports must be between 1 and 65535
+ERROR: mysite/tsuru.yml: kubernetes.groups.mysite.worker.ports[0]: protocol must be TCP or UDP, got "SCTP"
+`
+	assert.Equal(t, expected, tsuruCtx.Stdout.(*strings.Builder).String())
+}
+
+func TestAppValidateRunInvalidYaml(t *testing.T) {
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	afero.WriteFile(tsuruCtx.Fs, "mysite/Procfile", []byte(""), 0644)
+	afero.WriteFile(tsuruCtx.Fs, "mysite/tsuru.yaml", []byte("hooks:\n  build: make\n"), 0644)
+
+	cmd := newAppValidateCmd(tsuruCtx)
+	err := appValidateCmdRun(tsuruCtx, cmd, []string{"mysite"})
+	assert.EqualError(t, err, "found 2 error(s) on the app files")
+	expected := `ERROR: mysite/Procfile: no process declared
+ERROR: mysite/tsuru.yaml: unmarshal errors:
+  line 2: cannot unmarshal !!str ` + "`make`" + ` into []string
+`
+	assert.Equal(t, expected, tsuruCtx.Stdout.(*strings.Builder).String())
+}
+
+func TestAppValidateRunIgnoreFileAndFilesOnly(t *testing.T) {
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	afero.WriteFile(tsuruCtx.Fs, "mysite/Procfile", []byte("web: ./run"), 0644)
+	afero.WriteFile(tsuruCtx.Fs, ".tsuruignore", []byte("Procfile\n"), 0644)
+	afero.WriteFile(tsuruCtx.Fs, "mysite/config/app.conf", []byte("a=1"), 0644)
+	afero.WriteFile(tsuruCtx.Fs, "other/config/app.conf", []byte("a=2"), 0644)
+	afero.WriteFile(tsuruCtx.Fs, "other/Procfile", []byte("web: ./run"), 0644)
+
+	cmd := newAppValidateCmd(tsuruCtx)
+	err := appValidateCmdRun(tsuruCtx, cmd, []string{"mysite"})
+	assert.NoError(t, err)
+	expected := `WARNING: mysite/Procfile: the Procfile is excluded by .tsuruignore, so it won't be deployed
+WARNING: no Procfile found on the root of the deployed files, the platform's default process is used (if any)
+`
+	assert.Equal(t, expected, tsuruCtx.Stdout.(*strings.Builder).String())
+
+	tsuruCtx.Fs.Remove(".tsuruignore")
+	tsuruCtx.Stdout = &strings.Builder{}
+	cmd = newAppValidateCmd(tsuruCtx)
+	cmd.Flags().Parse([]string{"--files-only"})
+	err = appValidateCmdRun(tsuruCtx, cmd, []string{"other/Procfile", "mysite/config/app.conf", "other/config/app.conf"})
+	assert.NoError(t, err)
+	expected = `WARNING: other/config/app.conf: another file with the same name is already placed at "app.conf", so it won't be deployed (--files-only places every file on the root)
+`
+	assert.Equal(t, expected, tsuruCtx.Stdout.(*strings.Builder).String())
+}
+
+func TestAppDeployRunValidatesFiles(t *testing.T) {
+	deploys := 0
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			io.Copy(io.Discard, r.Body)
+			deploys++
+			fmt.Fprintln(w, "OK")
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+	afero.WriteFile(tsuruCtx.Fs, "mysite/Procfile", []byte("web: ./run"), 0644)
+	afero.WriteFile(tsuruCtx.Fs, "mysite/tsuru.yaml", []byte("healthcheck:\n  method: HEAD\n  path: /\n"), 0644)
+
+	appDeployCmd := newAppDeployCmd(tsuruCtx)
+	err := appDeployCmdRun(tsuruCtx, appDeployCmd, []string{"myapp", "mysite"})
+	assert.EqualError(t, err, "found 1 error(s) on the app files, fix them or use --skip-validation to deploy anyway")
+	assert.Contains(t, tsuruCtx.Stderr.(*strings.Builder).String(), `ERROR: mysite/tsuru.yaml: healthcheck.method: only GET is supported, got "HEAD"`)
+	assert.Equal(t, 0, deploys)
+
+	appDeployCmd = newAppDeployCmd(tsuruCtx)
+	appDeployCmd.Flags().Parse([]string{"--skip-validation"})
+	err = appDeployCmdRun(tsuruCtx, appDeployCmd, []string{"myapp", "mysite"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, deploys)
+}
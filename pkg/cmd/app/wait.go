@@ -0,0 +1,265 @@
+// Copyright © 2023 tsuru-client authors
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tsuru/tsuru-client/v2/internal/tsuructx"
+)
+
+func newAppWaitCmd(tsuruCtx *tsuructx.TsuruContext) *cobra.Command {
+	appWaitCmd := &cobra.Command{
+		Use:   "wait [APP]",
+		Short: "waits for the units of an app to be ready, deployed or stopped",
+		Long: `Waits until the units of an app (or of one of its processes or versions)
+reach a state, failing when [[--timeout]] expires first. It's useful on
+pipelines, eg: to wait for the app before running tests against it.
+
+The states that can be waited for ([[--for]]) are:
+  ready      all units are ready, like shown on app info
+  deployed   no deploy is running and all units are ready
+  stopped    all units are stopped (or gone)
+
+While waiting, the ready/total units of each process and version are shown.`,
+		Example: `$ tsuru app wait myapp
+$ tsuru app wait -a myapp --for deployed --timeout 10m
+$ tsuru app wait myapp --for stopped --process worker`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return appWaitCmdRun(tsuruCtx, cmd, args)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return completeAppNames(tsuruCtx, cmd, args, toComplete)
+		},
+		Args: cobra.RangeArgs(0, 1),
+	}
+
+	appWaitCmd.Flags().StringP("app", "a", "", "The name of the app (may be passed as argument)")
+	appWaitCmd.Flags().String("for", "ready", "The state to wait for: ready, deployed or stopped")
+	appWaitCmd.Flags().Duration("timeout", 5*time.Minute, "The maximum time to wait")
+	appWaitCmd.Flags().StringP("process", "p", "", "Wait only for the units of this process")
+	appWaitCmd.Flags().String("version", "", "Wait only for the units of this version")
+	return appWaitCmd
+}
+
+func appWaitCmdRun(tsuruCtx *tsuructx.TsuruContext, cmd *cobra.Command, args []string) error {
+	appName, err := appNameFromArgsOrFlag(cmd, args)
+	if err != nil {
+		return err
+	}
+	state := cmd.Flag("for").Value.String()
+	var done func([]unit) (bool, error)
+	switch state {
+	case "ready":
+		done = unitsCondition(allUnitsReady)
+	case "stopped":
+		done = unitsCondition(allUnitsStopped)
+	case "deployed":
+		done = func(units []unit) (bool, error) {
+			events, err := runningDeployEvents(tsuruCtx, appName)
+			if err != nil || len(events) > 0 {
+				return false, err
+			}
+			return allUnitsReady(units), nil
+		}
+	default:
+		return fmt.Errorf("invalid value %q for --for, it must be one of: ready, deployed or stopped", state)
+	}
+	cmd.SilenceUsage = true
+
+	filter := unitFilter{
+		process: cmd.Flag("process").Value.String(),
+		version: cmd.Flag("version").Value.String(),
+	}
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	status := &unitsStatusLine{out: tsuruCtx.Stdout, tty: isTerminalWriter(tsuruCtx.Stdout)}
+	err = waitForUnits(tsuruCtx, appName, filter, timeout, done, status.show)
+	status.finish()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(tsuruCtx.Stdout, "App %q is %s.\n", appName, state)
+	return nil
+}
+
+// waitPollInterval is how often the app is fetched while waiting for its units.
+var waitPollInterval = 2 * time.Second
+
+// unitFilter selects the units of a process and/or version (empty matches all).
+type unitFilter struct {
+	process string
+	version string
+}
+
+func (f unitFilter) match(u unit) bool {
+	if f.process != "" && u.ProcessName != f.process {
+		return false
+	}
+	return f.version == "" || strconv.Itoa(u.Version) == f.version
+}
+
+func (f unitFilter) String() string {
+	switch {
+	case f.process != "" && f.version != "":
+		return fmt.Sprintf("process %s (v%s)", f.process, f.version)
+	case f.process != "":
+		return "process " + f.process
+	case f.version != "":
+		return "version " + f.version
+	}
+	return "all processes"
+}
+
+// unitIsReady reports whether the unit is ready, like counted by app info and
+// app list.
+func unitIsReady(u unit) bool {
+	return u.ReadyAndStatus() == "ready"
+}
+
+// allUnitsReady is a wait condition satisfied when there are units and all of
+// them are ready.
+func allUnitsReady(units []unit) bool {
+	for _, u := range units {
+		if !unitIsReady(u) {
+			return false
+		}
+	}
+	return len(units) > 0
+}
+
+// allUnitsStopped is a wait condition satisfied when the units are gone or
+// stopped.
+func allUnitsStopped(units []unit) bool {
+	for _, u := range units {
+		if u.Status != "stopped" && u.Status != "asleep" {
+			return false
+		}
+	}
+	return true
+}
+
+// waitForUnits fetches the app every waitPollInterval until done is true for
+// its units selected by filter, failing when timeout expires first. When not
+// nil, progress is called with the units on every poll. Errors fetching the
+// app or returned by done after the first poll are retried until the timeout.
+func waitForUnits(tsuruCtx *tsuructx.TsuruContext, appName string, filter unitFilter, timeout time.Duration, done func([]unit) (bool, error), progress func([]unit)) error {
+	deadline := time.Now().Add(timeout)
+	for poll := 0; ; poll++ {
+		ok, err := pollUnits(tsuruCtx, appName, filter, done, progress)
+		if err == nil && ok {
+			return nil
+		}
+		if err != nil && poll == 0 {
+			return err
+		}
+		if time.Now().Add(waitPollInterval).After(deadline) {
+			if err != nil {
+				return fmt.Errorf("timeout after %s waiting for the units of app %q, last error: %w", timeout, appName, err)
+			}
+			return fmt.Errorf("timeout after %s waiting for the units of app %q", timeout, appName)
+		}
+		time.Sleep(waitPollInterval)
+	}
+}
+
+// pollUnits fetches the app once, returning whether done is true for its
+// units selected by filter.
+func pollUnits(tsuruCtx *tsuructx.TsuruContext, appName string, filter unitFilter, done func([]unit) (bool, error), progress func([]unit)) (bool, error) {
+	a, err := getApp(tsuruCtx, appName)
+	if err != nil {
+		return false, err
+	}
+	var units []unit
+	for _, u := range a.Units {
+		if filter.match(u) {
+			units = append(units, u)
+		}
+	}
+	if progress != nil {
+		progress(units)
+	}
+	return done(units)
+}
+
+// unitsCondition adapts a condition on the units to waitForUnits.
+func unitsCondition(cond func([]unit) bool) func([]unit) (bool, error) {
+	return func(units []unit) (bool, error) {
+		return cond(units), nil
+	}
+}
+
+// unitsStatusLine shows the ready/total units of each process and version:
+// redrawn on the same line on terminals, or on a new line when it changes
+// otherwise.
+type unitsStatusLine struct {
+	out  io.Writer
+	tty  bool
+	last string
+}
+
+func (s *unitsStatusLine) show(units []unit) {
+	line := unitsStatus(units)
+	if line == s.last {
+		return
+	}
+	s.last = line
+	if s.tty {
+		fmt.Fprintf(s.out, "\r%s\033[K", line)
+		return
+	}
+	fmt.Fprintln(s.out, line)
+}
+
+func (s *unitsStatusLine) finish() {
+	if s.tty && s.last != "" {
+		fmt.Fprintln(s.out)
+	}
+}
+
+// unitsStatus returns the ready/total units of each process and version, eg:
+// "web (v2): 1/2 ready, worker (v2): 1/1 ready".
+func unitsStatus(units []unit) string {
+	if len(units) == 0 {
+		return "no units"
+	}
+	type processVersion struct {
+		process string
+		version int
+	}
+	total := map[processVersion]int{}
+	ready := map[processVersion]int{}
+	var keys []processVersion
+	for _, u := range units {
+		key := processVersion{u.ProcessName, u.Version}
+		if _, ok := total[key]; !ok {
+			keys = append(keys, key)
+		}
+		total[key]++
+		if unitIsReady(u) {
+			ready[key]++
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].version == keys[j].version {
+			return keys[i].process < keys[j].process
+		}
+		return keys[i].version < keys[j].version
+	})
+	var parts []string
+	for _, key := range keys {
+		name := key.process
+		if key.version > 0 {
+			name = fmt.Sprintf("%s (v%d)", key.process, key.version)
+		}
+		parts = append(parts, fmt.Sprintf("%s: %d/%d ready", name, ready[key], total[key]))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,180 @@
+// Copyright © 2023 tsuru-client authors
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tsuru/tsuru-client/v2/internal/tsuructx"
+)
+
+// waitMockServer serves the app with the units of each poll, repeating the
+// last ones.
+func waitMockServer(t *testing.T, polls ...string) *httptest.Server {
+	n := 0
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/1.0/apps/myapp", r.URL.Path)
+		units := polls[len(polls)-1]
+		if n < len(polls) {
+			units = polls[n]
+		}
+		n++
+		fmt.Fprintf(w, `{"name":"myapp","units":[%s]}`, units)
+	}))
+}
+
+func TestAppWaitRunReady(t *testing.T) {
+	fastWaitPoll(t)
+	mockServer := waitMockServer(t,
+		`{"ProcessName":"web","Version":2,"Ready":false},{"ProcessName":"worker","Version":2,"Ready":false}`,
+		`{"ProcessName":"web","Version":2,"Ready":true},{"ProcessName":"worker","Version":2,"Ready":false}`,
+		`{"ProcessName":"web","Version":2,"Ready":true},{"ProcessName":"worker","Version":2,"Ready":false}`,
+		`{"ProcessName":"web","Version":2,"Ready":true},{"ProcessName":"worker","Version":2,"Ready":true}`,
+	)
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+
+	cmd := newAppWaitCmd(tsuruCtx)
+	err := appWaitCmdRun(tsuruCtx, cmd, []string{"myapp"})
+	assert.NoError(t, err)
+	expected := `web (v2): 0/1 ready, worker (v2): 0/1 ready
+web (v2): 1/1 ready, worker (v2): 0/1 ready
+web (v2): 1/1 ready, worker (v2): 1/1 ready
+App "myapp" is ready.
+`
+	assert.Equal(t, expected, tsuruCtx.Stdout.(*strings.Builder).String())
+}
+
+func TestAppWaitRunStoppedOnTerminal(t *testing.T) {
+	fastWaitPoll(t)
+	mockServer := waitMockServer(t,
+		`{"ProcessName":"web","Status":"started"},{"ProcessName":"worker","Status":"started"}`,
+		`{"ProcessName":"web","Status":"started"}`,
+	)
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+	stdout := &fakeTerminal{}
+	tsuruCtx.Stdout = stdout
+
+	cmd := newAppWaitCmd(tsuruCtx)
+	cmd.Flags().Parse([]string{"--for", "stopped", "--process", "worker"})
+	err := appWaitCmdRun(tsuruCtx, cmd, []string{"myapp"})
+	assert.NoError(t, err)
+	assert.Equal(t, "\rworker: 0/1 ready\033[K\rno units\033[K\nApp \"myapp\" is stopped.\n", stdout.String())
+}
+
+func TestAppWaitRunDeployed(t *testing.T) {
+	fastWaitPoll(t)
+	eventPolls := 0
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/1.1/events" {
+			assert.Equal(t, "app.deploy", r.URL.Query().Get("kindname"))
+			eventPolls++
+			if eventPolls < 3 {
+				fmt.Fprintln(w, `[{"UniqueID":"ev1","Running":true}]`)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		fmt.Fprintln(w, `{"name":"myapp","units":[{"ProcessName":"web","Ready":true}]}`)
+	}))
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+
+	cmd := newAppWaitCmd(tsuruCtx)
+	cmd.Flags().Parse([]string{"-a", "myapp", "--for", "deployed"})
+	err := appWaitCmdRun(tsuruCtx, cmd, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, eventPolls)
+	assert.Equal(t, "web: 1/1 ready\nApp \"myapp\" is deployed.\n", tsuruCtx.Stdout.(*strings.Builder).String())
+}
+
+func TestAppWaitRunDeployedRetriesEventErrors(t *testing.T) {
+	fastWaitPoll(t)
+	eventPolls := 0
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/1.1/events" {
+			eventPolls++
+			switch eventPolls {
+			case 1:
+				fmt.Fprintln(w, `[{"UniqueID":"ev1","Running":true}]`)
+			case 2:
+				w.WriteHeader(http.StatusServiceUnavailable)
+			default:
+				w.WriteHeader(http.StatusNoContent)
+			}
+			return
+		}
+		fmt.Fprintln(w, `{"name":"myapp","units":[{"ProcessName":"web","Ready":true}]}`)
+	}))
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+
+	cmd := newAppWaitCmd(tsuruCtx)
+	cmd.Flags().Parse([]string{"-a", "myapp", "--for", "deployed"})
+	err := appWaitCmdRun(tsuruCtx, cmd, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, eventPolls)
+	assert.Equal(t, "web: 1/1 ready\nApp \"myapp\" is deployed.\n", tsuruCtx.Stdout.(*strings.Builder).String())
+}
+
+func TestAppWaitRunTimeout(t *testing.T) {
+	fastWaitPoll(t)
+	mockServer := waitMockServer(t, `{"ProcessName":"web","Ready":false}`)
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+
+	cmd := newAppWaitCmd(tsuruCtx)
+	cmd.Flags().Parse([]string{"--timeout", "5ms"})
+	err := appWaitCmdRun(tsuruCtx, cmd, []string{"myapp"})
+	assert.EqualError(t, err, `timeout after 5ms waiting for the units of app "myapp"`)
+	assert.Equal(t, "web: 0/1 ready\n", tsuruCtx.Stdout.(*strings.Builder).String())
+}
+
+func TestAppWaitRunInvalidState(t *testing.T) {
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	cmd := newAppWaitCmd(tsuruCtx)
+	cmd.Flags().Parse([]string{"--for", "running"})
+	err := appWaitCmdRun(tsuruCtx, cmd, []string{"myapp"})
+	assert.EqualError(t, err, `invalid value "running" for --for, it must be one of: ready, deployed or stopped`)
+}
+
+func TestAppWaitRunStartedIsNotReady(t *testing.T) {
+	fastWaitPoll(t)
+	mockServer := waitMockServer(t, `{"ProcessName":"web","Status":"started"}`)
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+
+	cmd := newAppWaitCmd(tsuruCtx)
+	cmd.Flags().Parse([]string{"--timeout", "5ms"})
+	err := appWaitCmdRun(tsuruCtx, cmd, []string{"myapp"})
+	assert.EqualError(t, err, `timeout after 5ms waiting for the units of app "myapp"`)
+}
+
+func TestAppWaitRunRetriesErrors(t *testing.T) {
+	fastWaitPoll(t)
+	polls := 0
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		polls++
+		if polls == 2 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		fmt.Fprintf(w, `{"name":"myapp","units":[{"ProcessName":"web","Ready":%v}]}`, polls > 2)
+	}))
+	tsuruCtx := tsuructx.TsuruContextWithConfig(nil)
+	tsuruCtx.SetTargetURL(mockServer.URL)
+
+	cmd := newAppWaitCmd(tsuruCtx)
+	err := appWaitCmdRun(tsuruCtx, cmd, []string{"myapp"})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, polls)
+}
@@ -5,6 +5,7 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"strings"
@@ -16,6 +17,7 @@ import (
 	"github.com/tsuru/tsuru-client/v2/internal/config"
 	"github.com/tsuru/tsuru-client/v2/internal/exec"
 	"github.com/tsuru/tsuru-client/v2/internal/tsuructx"
+	"github.com/tsuru/tsuru-client/v2/internal/watch"
 	"github.com/tsuru/tsuru-client/v2/pkg/cmd/app"
 	"github.com/tsuru/tsuru-client/v2/pkg/cmd/auth"
 )
@@ -53,6 +55,10 @@ func Execute(_version, _commit, _dateStr string) {
 	version = cmdVersion{_version, _commit, _dateStr}
 	rootCmd := NewRootCmd(viper.GetViper(), nil)
 	err := rootCmd.Execute()
+	var exitErr interface{ ExitCode() int }
+	if errors.As(err, &exitErr) {
+		os.Exit(exitErr.ExitCode())
+	}
 	if err != nil {
 		os.Exit(1)
 	}
@@ -178,6 +184,7 @@ func setupPFlagsAndCommands(rootCmd *cobra.Command, tsuruCtx *tsuructx.TsuruCont
 	rootCmd.PersistentFlags().Bool("json", false, "return the output in json format (when possible)") // TODO: add to PersistentPreRun()
 	rootCmd.PersistentFlags().String("target", "", "Tsuru server endpoint")
 	rootCmd.PersistentFlags().IntP("verbosity", "v", 0, "Verbosity level: 1 => print HTTP requests; 2 => print HTTP requests/responses")
+	watch.AddFlag(rootCmd)
 
 	if cfgFile != "" {
 		// Use config file from the flag.
@@ -201,6 +208,7 @@ func setupPFlagsAndCommands(rootCmd *cobra.Command, tsuruCtx *tsuructx.TsuruCont
 	for _, cmd := range commands {
 		rootCmd.AddCommand(cmd(tsuruCtx))
 	}
+	watch.SetupCommands(rootCmd, tsuruCtx)
 
 	v1LegacyCmdManager := newV1LegacyCmdManager()
 	addMissingV1LegacyCommands(rootCmd, v1LegacyCmdManager)
@@ -32,7 +32,9 @@ type TableViewOptions struct {
 	CustomFieldFunc map[string]CustomFieldFunc
 }
 
-func (o *TableViewOptions) isFieldVisible(field string) bool {
+// IsFieldVisible reports whether the field is shown, according to ShowFields
+// and HiddenFields. All fields are visible with nil options.
+func (o *TableViewOptions) IsFieldVisible(field string) bool {
 	if o == nil {
 		return true
 	}
@@ -57,7 +59,7 @@ func (o *TableViewOptions) visibleFieldsFromMap(m map[string]any) []string {
 func (o *TableViewOptions) visibleFieldsFromSlice(ss []string) []string {
 	ret := make([]string, 0, len(ss))
 	for _, s := range ss {
-		if o.isFieldVisible(s) {
+		if o.IsFieldVisible(s) {
 			ret = append(ret, s)
 		}
 	}
@@ -150,7 +152,7 @@ func printTableAny(out io.Writer, data any, opts *TableViewOptions) (err error)
 	// No custom printer found, try to print as best as we can
 	dt := reflect.TypeOf(data)
 	for _, field := range reflect.VisibleFields(dt) {
-		if !opts.isFieldVisible(field.Name) {
+		if !opts.IsFieldVisible(field.Name) {
 			continue
 		}
 